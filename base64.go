@@ -0,0 +1,39 @@
+package tspl
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	bin_img "github.com/haxii/tspl/bin-img"
+)
+
+// ToBitmapBase64 encodes b as a BITMAP command, like Image2Bytes, but with
+// the binary payload base64 encoded so the command survives text formats such
+// as JSON, e.g. "BITMAP 0,0,2,16,1,AAD/...". FromBitmapBase64 reverses it.
+func (t *Driver) ToBitmapBase64(b *bin_img.Binary) (string, error) {
+	headerSize, bitmap, err := t.Image2Bytes(b)
+	if err != nil {
+		return "", err
+	}
+	return string(bitmap[:headerSize]) + base64.StdEncoding.EncodeToString(bitmap[headerSize:]), nil
+}
+
+// FromBitmapBase64 decodes a command made by ToBitmapBase64. The header is
+// kept as is, so re-encoding the returned Image gives back the same command.
+func (t *Driver) FromBitmapBase64(s string) (*Image, error) {
+	h, err := parseBitmapHeader([]byte(s))
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(s[h.HeaderEnd:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid BITMAP payload: %w", err)
+	}
+	if len(data) != h.RowBytes*h.Height {
+		return nil, fmt.Errorf("bitmap data is %d bytes, want %d", len(data), h.RowBytes*h.Height)
+	}
+	body := make([]byte, 0, h.HeaderEnd+len(data))
+	body = append(body, s[:h.HeaderEnd]...)
+	body = append(body, data...)
+	return t.Bytes2Image(body)
+}
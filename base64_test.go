@@ -0,0 +1,64 @@
+package tspl
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+func TestBitmapBase64RoundTrip(t *testing.T) {
+	sheet, err := bin_img.NewSpriteSheet(32, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			if (x*5+y*3)%7 < 3 {
+				sheet.Sheet().SetOn(x, y)
+			}
+		}
+	}
+	if err := sheet.AddSprite("icon", image.Rect(5, 3, 25, 10)); err != nil {
+		t.Fatal(err)
+	}
+	sprite, err := sheet.Sprite("icon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name   string
+		img    *bin_img.Binary
+		header string
+	}{
+		{"whole sheet", sheet.Sheet(), "BITMAP 0,0,4,16,1,"},
+		{"sprite view", sprite, "BITMAP 0,0,3,7,1,"},
+		{"unaligned SubImage", sheet.Sheet().SubImage(image.Rect(1, 0, 32, 16)).(*bin_img.Binary), "BITMAP 0,0,4,16,1,"},
+	} {
+		s, err := DefaultDriver.ToBitmapBase64(tt.img)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !strings.HasPrefix(s, tt.header) {
+			t.Errorf("%s: got %.20q, want header %q", tt.name, s, tt.header)
+		}
+		img, err := DefaultDriver.FromBitmapBase64(s)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(img.Header) != tt.header {
+			t.Errorf("%s: decoded header %q, want %q", tt.name, img.Header, tt.header)
+		}
+		if !samePixels(tt.img, img.Bitmap) {
+			t.Errorf("%s: round trip changed the image", tt.name)
+		}
+		again, err := DefaultDriver.ToBitmapBase64(img.Bitmap)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != s {
+			t.Errorf("%s: re-encoding gave %q, want %q", tt.name, again, s)
+		}
+	}
+}
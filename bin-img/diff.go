@@ -0,0 +1,48 @@
+package bin_img
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// diffPalette colours WriteDiffPNG's output: unchanged pixels in light or
+// dark gray for on or off, pixels on in a only reddish and pixels on in b
+// only bluish.
+var diffPalette = color.Palette{
+	color.Gray{0xE0},
+	color.Gray{0x60},
+	color.RGBA{0xE0, 0x30, 0x30, 0xFF},
+	color.RGBA{0x30, 0x50, 0xE0, 0xFF},
+}
+
+// WriteDiffPNG writes a PNG showing where a and b differ and returns the
+// number of differing pixels. a and b must be the same size; their top-left
+// corners are compared with each other.
+func WriteDiffPNG(w io.Writer, a, b *Binary) (diffCount int, err error) {
+	if a.Rect.Size() != b.Rect.Size() {
+		return 0, errors.New("binimg: images differ in size")
+	}
+	p := image.NewPaletted(image.Rect(0, 0, a.Rect.Dx(), a.Rect.Dy()), diffPalette)
+	for y := 0; y < a.Rect.Dy(); y++ {
+		for x := 0; x < a.Rect.Dx(); x++ {
+			ia := a.bit(a.Rect.Min.X+x, a.Rect.Min.Y+y)
+			ib := b.bit(b.Rect.Min.X+x, b.Rect.Min.Y+y)
+			var idx uint8
+			switch {
+			case ia && ib:
+				idx = 0
+			case !ia && !ib:
+				idx = 1
+			case ia:
+				idx, diffCount = 2, diffCount+1
+			default:
+				idx, diffCount = 3, diffCount+1
+			}
+			p.SetColorIndex(x, y, idx)
+		}
+	}
+	return diffCount, png.Encode(w, p)
+}
@@ -0,0 +1,348 @@
+package bin_img
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// DrawLine sets the pixels on the line from (x0,y0) to (x1,y1), both ends
+// included, using Bresenham's algorithm. The line is clipped to Rect first.
+func (b *Binary) DrawLine(x0, y0, x1, y1 int, on bool) {
+	b.line(x0, y0, x1, y1, func(int) bool { return true }, on)
+}
+
+// DrawDashedLine is like DrawLine but draws dashLen pixels, skips gapLen
+// pixels, and repeats. The pattern always starts with a dash at (x0,y0) and
+// advances one step per pixel along the major axis, so segments drawn from
+// a shared start point line up, even where the start is clipped away. A line
+// shorter than one dash is drawn solid. Nothing is drawn if dashLen is not
+// positive; a gapLen of zero or less draws a solid line.
+func (b *Binary) DrawDashedLine(x0, y0, x1, y1 int, on bool, dashLen, gapLen int) {
+	if dashLen <= 0 {
+		return
+	}
+	if gapLen <= 0 {
+		b.DrawLine(x0, y0, x1, y1, on)
+		return
+	}
+	period := dashLen + gapLen
+	b.line(x0, y0, x1, y1, func(step int) bool { return step%period < dashLen }, on)
+}
+
+// line clips the line from (x0,y0) to (x1,y1) to Rect, runs Bresenham over
+// what is left and sets each pixel for whose step index draw returns true.
+// Steps count from the unclipped start.
+func (b *Binary) line(x0, y0, x1, y1 int, draw func(step int) bool, on bool) {
+	cx0, cy0, cx1, cy1, visible := ClipLine(b.Rect, x0, y0, x1, y1)
+	if !visible {
+		return
+	}
+	skipped := abs(cx0 - x0)
+	if d := abs(cy0 - y0); d > skipped {
+		skipped = d
+	}
+	x0, y0, x1, y1 = cx0, cy0, cx1, cy1
+
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for step := skipped; ; step++ {
+		if draw(step) {
+			b.setBit(x0, y0, on)
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// Cohen–Sutherland outcodes.
+const (
+	outLeft = 1 << iota
+	outRight
+	outTop
+	outBottom
+)
+
+// ClipLine clips the line from (x0,y0) to (x1,y1) to the pixels of r using
+// the Cohen–Sutherland algorithm. It returns the clipped end points, rounded
+// to the nearest pixel, and whether any part of the line lies within r.
+func ClipLine(r image.Rectangle, x0, y0, x1, y1 int) (nx0, ny0, nx1, ny1 int, visible bool) {
+	if r.Empty() {
+		return 0, 0, 0, 0, false
+	}
+	// r is half-open; clip against its last pixel row and column.
+	minX, minY := float64(r.Min.X), float64(r.Min.Y)
+	maxX, maxY := float64(r.Max.X-1), float64(r.Max.Y-1)
+	outcode := func(x, y float64) int {
+		code := 0
+		if x < minX {
+			code |= outLeft
+		} else if x > maxX {
+			code |= outRight
+		}
+		if y < minY {
+			code |= outTop
+		} else if y > maxY {
+			code |= outBottom
+		}
+		return code
+	}
+
+	fx0, fy0, fx1, fy1 := float64(x0), float64(y0), float64(x1), float64(y1)
+	c0, c1 := outcode(fx0, fy0), outcode(fx1, fy1)
+	for {
+		if c0|c1 == 0 {
+			return int(math.Round(fx0)), int(math.Round(fy0)), int(math.Round(fx1)), int(math.Round(fy1)), true
+		}
+		if c0&c1 != 0 {
+			return 0, 0, 0, 0, false
+		}
+		c := c0
+		if c == 0 {
+			c = c1
+		}
+		var x, y float64
+		switch {
+		case c&outTop != 0:
+			x, y = fx0+(fx1-fx0)*(minY-fy0)/(fy1-fy0), minY
+		case c&outBottom != 0:
+			x, y = fx0+(fx1-fx0)*(maxY-fy0)/(fy1-fy0), maxY
+		case c&outLeft != 0:
+			x, y = minX, fy0+(fy1-fy0)*(minX-fx0)/(fx1-fx0)
+		default:
+			x, y = maxX, fy0+(fy1-fy0)*(maxX-fx0)/(fx1-fx0)
+		}
+		if c == c0 {
+			fx0, fy0 = x, y
+			c0 = outcode(x, y)
+		} else {
+			fx1, fy1 = x, y
+			c1 = outcode(x, y)
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// DrawPolygon draws the outline of the polygon through pts, closing it back
+// to the first point. Fewer than 3 points draw nothing.
+func (b *Binary) DrawPolygon(pts []image.Point, on bool) {
+	if len(pts) < 3 {
+		return
+	}
+	for i, p := range pts {
+		q := pts[(i+1)%len(pts)]
+		b.DrawLine(p.X, p.Y, q.X, q.Y, on)
+	}
+}
+
+// FillPolygon fills the polygon through pts by scanline, setting each pixel
+// whose centre lies inside by the non-zero winding rule, so convex, concave
+// and self-intersecting shapes such as stars fill solid. Fewer than 3 points
+// fill nothing.
+func (b *Binary) FillPolygon(pts []image.Point, on bool) {
+	if len(pts) < 3 {
+		return
+	}
+	minY, maxY := pts[0].Y, pts[0].Y
+	for _, p := range pts[1:] {
+		if p.Y < minY {
+			minY = p.Y
+		}
+		if p.Y > maxY {
+			maxY = p.Y
+		}
+	}
+	if minY < b.Rect.Min.Y {
+		minY = b.Rect.Min.Y
+	}
+	if maxY > b.Rect.Max.Y {
+		maxY = b.Rect.Max.Y
+	}
+	// crossing is where an edge crosses a scanline and whether it goes down.
+	type crossing struct {
+		x    float64
+		wind int
+	}
+	var xs []crossing
+	for y := minY; y < maxY; y++ {
+		cy := float64(y) + 0.5
+		xs = xs[:0]
+		for i, p := range pts {
+			q := pts[(i+1)%len(pts)]
+			y0, y1 := float64(p.Y), float64(q.Y)
+			if (y0 <= cy) == (y1 <= cy) {
+				continue
+			}
+			wind := 1
+			if y1 < y0 {
+				wind = -1
+			}
+			xs = append(xs, crossing{float64(p.X) + (cy-y0)*float64(q.X-p.X)/(y1-y0), wind})
+		}
+		sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+		wind := 0
+		for i := 0; i+1 < len(xs); i++ {
+			wind += xs[i].wind
+			if wind == 0 {
+				continue
+			}
+			// Pixels whose centre x+0.5 lies in [xs[i].x, xs[i+1].x).
+			x0 := int(math.Ceil(xs[i].x - 0.5))
+			x1 := int(math.Ceil(xs[i+1].x - 0.5))
+			if x0 < b.Rect.Min.X {
+				x0 = b.Rect.Min.X
+			}
+			if x1 > b.Rect.Max.X {
+				x1 = b.Rect.Max.X
+			}
+			for x := x0; x < x1; x++ {
+				b.setBit(x, y, on)
+			}
+		}
+	}
+}
+
+// FillCircle sets the pixels whose centres lie within r of the centre of
+// pixel (cx,cy), a disc 2r+1 pixels across, one span per row. It is fast at
+// any size but its edges are jagged at small radii; see FillCircleAA.
+func (b *Binary) FillCircle(cx, cy, r int, on bool) {
+	if r < 0 {
+		return
+	}
+	for dy := -r; dy <= r; dy++ {
+		y := cy + dy
+		if y < b.Rect.Min.Y || y >= b.Rect.Max.Y {
+			continue
+		}
+		dx := int(math.Sqrt(float64(r*r - dy*dy)))
+		x0, x1 := cx-dx, cx+dx+1
+		if x0 < b.Rect.Min.X {
+			x0 = b.Rect.Min.X
+		}
+		if x1 > b.Rect.Max.X {
+			x1 = b.Rect.Max.X
+		}
+		for x := x0; x < x1; x++ {
+			b.setBit(x, y, on)
+		}
+	}
+}
+
+// circleAASamples is how many sub-pixels per axis FillCircleAA samples.
+const circleAASamples = 4
+
+// FillCircleAA is like FillCircle but for the true circle through the outer
+// edges of that disc's extreme pixels, radius r+0.5 around the centre of
+// pixel (cx,cy): each pixel is sampled at 4x4 sub-pixels and set if at least
+// coverageThresh of them, from 0 to 1, lie inside. 0.5 matches the circle's
+// area; lower values give a bolder disc. Only pixels on the edge are
+// sampled, so it is nearly as fast as FillCircle, which remains the simpler
+// choice for large radii where aliasing is invisible.
+func (b *Binary) FillCircleAA(cx, cy, r int, coverageThresh float64, on bool) {
+	if r < 0 {
+		return
+	}
+	rad := float64(r) + 0.5
+	need := int(math.Ceil(coverageThresh * circleAASamples * circleAASamples))
+	if need < 1 {
+		need = 1
+	}
+	rect := image.Rect(cx-r, cy-r, cx+r+1, cy+r+1).Intersect(b.Rect)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			// d is the distance between the pixel's and the circle's centres;
+			// a pixel reaches at most √2/2 from its centre.
+			d := math.Hypot(float64(x-cx), float64(y-cy))
+			n := 0
+			switch {
+			case d <= rad-math.Sqrt2/2:
+				n = circleAASamples * circleAASamples
+			case d < rad+math.Sqrt2/2:
+				for j := 0; j < circleAASamples; j++ {
+					sy := float64(y-cy) - 0.5 + (float64(j)+0.5)/circleAASamples
+					for i := 0; i < circleAASamples; i++ {
+						sx := float64(x-cx) - 0.5 + (float64(i)+0.5)/circleAASamples
+						if sx*sx+sy*sy <= rad*rad {
+							n++
+						}
+					}
+				}
+			}
+			if n >= need {
+				b.setBit(x, y, on)
+			}
+		}
+	}
+}
+
+// CopyFrom copies the srcRect part of src into b with its top-left corner
+// at (dstX,dstY), overwriting both on and off pixels. Pixels of srcRect
+// outside src and those landing outside b are skipped; the rest keep their
+// place relative to (dstX,dstY). Rows are copied up to 8 pixels at a time,
+// shifting bits when the source and destination are not byte-aligned with
+// each other.
+//
+// src may be b itself, but not a different view sharing b's pixels whose
+// area overlaps the destination.
+func (b *Binary) CopyFrom(src *Binary, dstX, dstY int, srcRect image.Rectangle) {
+	if src == b {
+		src = b.Snapshot()
+	}
+	// Take the offset before clipping, so that clipping srcRect drops the
+	// pixels it cuts off instead of moving the rest.
+	delta := image.Pt(dstX, dstY).Sub(srcRect.Min)
+	srcRect = srcRect.Intersect(src.Rect)
+	dst := srcRect.Add(delta).Intersect(b.Rect)
+	if dst.Empty() {
+		return
+	}
+	for y := dst.Min.Y; y < dst.Max.Y; y++ {
+		sy := y - delta.Y
+		for x := dst.Min.X; x < dst.Max.X; {
+			bitPos := x & 7
+			n := 8 - bitPos
+			if r := dst.Max.X - x; r < n {
+				n = r
+			}
+			v := src.bits(x-delta.X, sy, n)
+			mask := byte(0xFF<<uint(8-n)) >> uint(bitPos)
+			i := b.pixOffset(x, y)
+			b.Pix[i] = b.Pix[i]&^mask | v>>uint(bitPos)&mask
+			x += n
+		}
+	}
+}
+
+// bits returns the n (at most 8) pixels starting at (x,y) in the top bits of
+// a byte. They must lie within Rect.
+func (b *Binary) bits(x, y, n int) byte {
+	i := b.pixOffset(x, y)
+	w := uint16(b.Pix[i]) << 8
+	if x&7+n > 8 {
+		w |= uint16(b.Pix[i+1])
+	}
+	return byte(w << uint(x&7) >> 8)
+}
@@ -0,0 +1,49 @@
+package bin_img
+
+import (
+	"image"
+	"testing"
+)
+
+// copyFromRef is CopyFrom done one pixel at a time.
+func copyFromRef(b, src *Binary, dstX, dstY int, srcRect image.Rectangle) {
+	for y := srcRect.Min.Y; y < srcRect.Max.Y; y++ {
+		for x := srcRect.Min.X; x < srcRect.Max.X; x++ {
+			p := image.Pt(x-srcRect.Min.X+dstX, y-srcRect.Min.Y+dstY)
+			if image.Pt(x, y).In(src.Rect) && p.In(b.Rect) {
+				b.setBit(p.X, p.Y, src.bit(x, y))
+			}
+		}
+	}
+}
+
+func TestCopyFromMatchesReference(t *testing.T) {
+	src := noise(t, 48, 24).SubImage(image.Rect(3, 2, 45, 21)).(*Binary)
+	for _, dstView := range []image.Rectangle{
+		image.Rect(0, 0, 40, 20),
+		image.Rect(5, 1, 37, 19),
+		image.Rect(8, 4, 33, 12),
+	} {
+		for _, tt := range []struct {
+			at      image.Point
+			srcRect image.Rectangle
+		}{
+			{image.Pt(8, 4), image.Rect(8, 4, 24, 12)},    // aligned
+			{image.Pt(11, 5), image.Rect(6, 3, 25, 14)},   // shifted
+			{image.Pt(-4, -3), image.Rect(3, 2, 20, 10)},  // clipped left and top
+			{image.Pt(30, 15), image.Rect(10, 5, 30, 15)}, // clipped right and bottom
+			{image.Pt(-2, 0), image.Rect(0, 0, 60, 30)},   // source clipped on every side
+			{image.Pt(2, 2), image.Rect(13, 6, 14, 7)},    // one pixel
+			{image.Pt(50, 2), image.Rect(3, 2, 10, 10)},   // entirely outside
+		} {
+			got := noise(t, 40, 20).SubImage(dstView).(*Binary)
+			want := noise(t, 40, 20).SubImage(dstView).(*Binary)
+			got.CopyFrom(src, tt.at.X, tt.at.Y, tt.srcRect)
+			copyFromRef(want, src, tt.at.X, tt.at.Y, tt.srcRect)
+			if !sameBinary(got, want) {
+				t.Errorf("CopyFrom of %v to %v in view %v differs from the per-pixel copy",
+					tt.srcRect, tt.at, dstView)
+			}
+		}
+	}
+}
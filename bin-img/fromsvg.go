@@ -0,0 +1,812 @@
+package bin_img
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FromSVG renders a monochrome SVG to a w x h Binary, scaled to fit by the
+// root viewBox (or width and height) and centred as SVG's default
+// preserveAspectRatio does. As with NewBinary, w must be a multiple of 8.
+//
+// Only the simple drawings design tools export for label artwork are
+// supported: path, rect, circle, ellipse, line, polyline and polygon
+// elements inside nested g elements, with fill, stroke, stroke-width,
+// fill-rule and transform given as attributes or in a style attribute. Dark
+// paint is ink and renders off, light paint renders on, over an on
+// background. Fills are scanline filled; strokes one pixel wide or thinner
+// are drawn with DrawLine and wider ones as filled segments with round
+// joins. Text, images, gradients, patterns and CSS classes are not rendered;
+// a paint referencing a gradient or pattern is an error.
+func FromSVG(svgData []byte, w, h int) (*Binary, error) {
+	b, err := NewBinary(w, h)
+	if err != nil {
+		return nil, err
+	}
+	b.Fill(true)
+	r := svgRenderer{b: b, w: float64(w), h: float64(h)}
+	if err := r.render(svgData); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// svgStyle is the inherited drawing state of an SVG element.
+type svgStyle struct {
+	fill, stroke svgPaint
+	strokeWidth  float64
+	evenOdd      bool
+	m            affine
+}
+
+// svgPaint is a fill or stroke: nothing, ink or paper.
+type svgPaint struct {
+	none, ink bool
+}
+
+// affine is the matrix [a c e; b d f; 0 0 1] as SVG writes it.
+type affine [6]float64
+
+var identity = affine{1, 0, 0, 1, 0, 0}
+
+// mul returns m applied after n.
+func (m affine) mul(n affine) affine {
+	return affine{
+		m[0]*n[0] + m[2]*n[1], m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3], m[1]*n[2] + m[3]*n[3],
+		m[0]*n[4] + m[2]*n[5] + m[4], m[1]*n[4] + m[3]*n[5] + m[5],
+	}
+}
+
+func (m affine) apply(x, y float64) pt {
+	return pt{m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]}
+}
+
+// scale is the factor m scales lengths by on average.
+func (m affine) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+type pt struct{ x, y float64 }
+
+// contour is one subpath of a shape. Open subpaths are filled as if closed
+// but not stroked so.
+type contour struct {
+	pts    []pt
+	closed bool
+}
+
+type svgRenderer struct {
+	b    *Binary
+	w, h float64
+}
+
+func (r *svgRenderer) render(data []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	stack := []svgStyle{{fill: svgPaint{ink: true}, stroke: svgPaint{none: true}, strokeWidth: 1, m: identity}}
+	root := true
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.New("binimg: invalid SVG: " + err.Error())
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.StartElement:
+			attrs := svgAttrs(t.Attr)
+			st, err := stack[len(stack)-1].inherit(attrs)
+			if err != nil {
+				return err
+			}
+			if root {
+				if t.Name.Local != "svg" {
+					return errors.New("binimg: not an SVG document")
+				}
+				st.m = r.viewport(attrs).mul(st.m)
+				root = false
+			}
+			switch t.Name.Local {
+			case "svg", "g", "a":
+			case "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+				r.draw(shapeContours(t.Name.Local, attrs, st.m), st)
+			default:
+				if err := dec.Skip(); err != nil {
+					return errors.New("binimg: invalid SVG: " + err.Error())
+				}
+				continue
+			}
+			stack = append(stack, st)
+		}
+	}
+	if root {
+		return errors.New("binimg: not an SVG document")
+	}
+	return nil
+}
+
+// viewport maps the root element's user space onto the w x h image.
+func (r *svgRenderer) viewport(attrs map[string]string) affine {
+	var vb [4]float64
+	if f := svgNumbers(attrs["viewBox"]); len(f) == 4 && f[2] > 0 && f[3] > 0 {
+		copy(vb[:], f)
+	} else {
+		vb[2], vb[3] = svgLength(attrs["width"]), svgLength(attrs["height"])
+		if vb[2] <= 0 || vb[3] <= 0 {
+			return identity
+		}
+	}
+	s := math.Min(r.w/vb[2], r.h/vb[3])
+	return affine{s, 0, 0, s, (r.w-vb[2]*s)/2 - vb[0]*s, (r.h-vb[3]*s)/2 - vb[1]*s}
+}
+
+// svgAttrs collects an element's attributes, with the declarations of its
+// style attribute taking precedence.
+func svgAttrs(attr []xml.Attr) map[string]string {
+	m := make(map[string]string, len(attr))
+	for _, a := range attr {
+		m[a.Name.Local] = strings.TrimSpace(a.Value)
+	}
+	for _, decl := range strings.Split(m["style"], ";") {
+		if k, v, ok := strings.Cut(decl, ":"); ok {
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
+func (s svgStyle) inherit(attrs map[string]string) (svgStyle, error) {
+	var err error
+	if v, ok := attrs["fill"]; ok {
+		if s.fill, err = parsePaint(v); err != nil {
+			return s, err
+		}
+	}
+	if v, ok := attrs["stroke"]; ok {
+		if s.stroke, err = parsePaint(v); err != nil {
+			return s, err
+		}
+	}
+	if v, ok := attrs["stroke-width"]; ok {
+		s.strokeWidth = svgLength(v)
+	}
+	if v, ok := attrs["fill-rule"]; ok {
+		s.evenOdd = v == "evenodd"
+	}
+	if v, ok := attrs["transform"]; ok {
+		s.m = s.m.mul(parseTransform(v))
+	}
+	return s, nil
+}
+
+// parsePaint reads a fill or stroke value. Colors darker than mid-gray are
+// ink.
+func parsePaint(v string) (svgPaint, error) {
+	v = strings.ToLower(v)
+	switch {
+	case v == "none" || v == "transparent":
+		return svgPaint{none: true}, nil
+	case strings.HasPrefix(v, "url("):
+		return svgPaint{}, errors.New("binimg: unsupported SVG paint " + v)
+	case v == "currentcolor" || v == "black":
+		return svgPaint{ink: true}, nil
+	case v == "white":
+		return svgPaint{}, nil
+	case strings.HasPrefix(v, "#"):
+		hex := v[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if len(hex) != 6 || err != nil {
+			break
+		}
+		return paintOf(float64(n>>16), float64(n>>8&0xff), float64(n&0xff)), nil
+	case strings.HasPrefix(v, "rgb(") && strings.HasSuffix(v, ")"):
+		f := svgNumbers(v[4 : len(v)-1])
+		if len(f) != 3 {
+			break
+		}
+		return paintOf(f[0], f[1], f[2]), nil
+	}
+	// Other named colors are mostly saturated and print as ink.
+	return svgPaint{ink: true}, nil
+}
+
+func paintOf(r, g, b float64) svgPaint {
+	return svgPaint{ink: 0.299*r+0.587*g+0.114*b < 128}
+}
+
+// parseTransform reads a transform list such as "translate(10,5) scale(2)".
+// Unknown or malformed transforms are ignored.
+func parseTransform(v string) affine {
+	m := identity
+	for {
+		open := strings.IndexByte(v, '(')
+		end := strings.IndexByte(v, ')')
+		if open < 0 || end < open {
+			return m
+		}
+		name := strings.Trim(v[:open], " ,\t\r\n")
+		a := svgNumbers(v[open+1 : end])
+		v = v[end+1:]
+		var t affine
+		switch {
+		case name == "matrix" && len(a) == 6:
+			copy(t[:], a)
+		case name == "translate" && len(a) == 1:
+			t = affine{1, 0, 0, 1, a[0], 0}
+		case name == "translate" && len(a) == 2:
+			t = affine{1, 0, 0, 1, a[0], a[1]}
+		case name == "scale" && len(a) == 1:
+			t = affine{a[0], 0, 0, a[0], 0, 0}
+		case name == "scale" && len(a) == 2:
+			t = affine{a[0], 0, 0, a[1], 0, 0}
+		case name == "rotate" && (len(a) == 1 || len(a) == 3):
+			sin, cos := math.Sincos(a[0] * math.Pi / 180)
+			t = affine{cos, sin, -sin, cos, 0, 0}
+			if len(a) == 3 {
+				t = affine{1, 0, 0, 1, a[1], a[2]}.mul(t).mul(affine{1, 0, 0, 1, -a[1], -a[2]})
+			}
+		case name == "skewX" && len(a) == 1:
+			t = affine{1, 0, math.Tan(a[0] * math.Pi / 180), 1, 0, 0}
+		case name == "skewY" && len(a) == 1:
+			t = affine{1, math.Tan(a[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			continue
+		}
+		m = m.mul(t)
+	}
+}
+
+// svgNumbers parses a list of numbers separated by spaces or commas.
+func svgNumbers(s string) []float64 {
+	var res []float64
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n' }) {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// svgLength parses a length in user units; a px suffix is allowed. Invalid
+// and non-finite lengths are 0.
+func svgLength(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+// shapeContours returns the outline of a basic shape or path in image
+// coordinates.
+func shapeContours(name string, attrs map[string]string, m affine) []contour {
+	num := func(k string) float64 { return svgLength(attrs[k]) }
+	p := pathBuilder{m: m}
+	switch name {
+	case "path":
+		p.parse(attrs["d"])
+	case "rect":
+		x, y, w, h := num("x"), num("y"), num("width"), num("height")
+		if w <= 0 || h <= 0 {
+			return nil
+		}
+		rx, ry := num("rx"), num("ry")
+		if _, ok := attrs["ry"]; !ok {
+			ry = rx
+		}
+		if _, ok := attrs["rx"]; !ok {
+			rx = ry
+		}
+		rx, ry = math.Min(rx, w/2), math.Min(ry, h/2)
+		if rx <= 0 || ry <= 0 {
+			p.moveTo(x, y)
+			p.lineTo(x+w, y)
+			p.lineTo(x+w, y+h)
+			p.lineTo(x, y+h)
+		} else {
+			p.moveTo(x+rx, y)
+			p.lineTo(x+w-rx, y)
+			p.arcTo(rx, ry, 0, false, true, x+w, y+ry)
+			p.lineTo(x+w, y+h-ry)
+			p.arcTo(rx, ry, 0, false, true, x+w-rx, y+h)
+			p.lineTo(x+rx, y+h)
+			p.arcTo(rx, ry, 0, false, true, x, y+h-ry)
+			p.lineTo(x, y+ry)
+			p.arcTo(rx, ry, 0, false, true, x+rx, y)
+		}
+		p.close()
+	case "circle", "ellipse":
+		cx, cy, rx, ry := num("cx"), num("cy"), num("rx"), num("ry")
+		if name == "circle" {
+			rx, ry = num("r"), num("r")
+		}
+		if rx <= 0 || ry <= 0 {
+			return nil
+		}
+		p.moveTo(cx+rx, cy)
+		p.arcTo(rx, ry, 0, false, true, cx-rx, cy)
+		p.arcTo(rx, ry, 0, false, true, cx+rx, cy)
+		p.close()
+	case "line":
+		p.moveTo(num("x1"), num("y1"))
+		p.lineTo(num("x2"), num("y2"))
+	case "polyline", "polygon":
+		f := svgNumbers(attrs["points"])
+		for i := 0; i+1 < len(f); i += 2 {
+			if i == 0 {
+				p.moveTo(f[0], f[1])
+			} else {
+				p.lineTo(f[i], f[i+1])
+			}
+		}
+		if name == "polygon" {
+			p.close()
+		}
+	}
+	p.flush()
+	return p.contours
+}
+
+// pathBuilder flattens path segments, in user space, into contours of
+// straight edges in image space.
+type pathBuilder struct {
+	m        affine
+	contours []contour
+	cur      []pt
+	// x, y is the current point and sx, sy the start of the subpath, in user
+	// space.
+	x, y, sx, sy float64
+}
+
+// flush ends the current subpath.
+func (p *pathBuilder) flush() {
+	p.end(false)
+}
+
+func (p *pathBuilder) end(closed bool) {
+	if len(p.cur) > 1 {
+		p.contours = append(p.contours, contour{p.cur, closed})
+	}
+	p.cur = nil
+}
+
+func (p *pathBuilder) moveTo(x, y float64) {
+	p.flush()
+	p.x, p.y, p.sx, p.sy = x, y, x, y
+	p.cur = []pt{p.m.apply(x, y)}
+}
+
+func (p *pathBuilder) lineTo(x, y float64) {
+	if p.cur == nil {
+		p.cur = []pt{p.m.apply(p.x, p.y)}
+	}
+	p.x, p.y = x, y
+	p.cur = append(p.cur, p.m.apply(x, y))
+}
+
+// close ends the subpath; edges back to its start are implied.
+func (p *pathBuilder) close() {
+	p.end(true)
+	p.x, p.y = p.sx, p.sy
+}
+
+// steps is how many line segments approximate a curve whose control polygon
+// is pts, in user space: about one per 2 pixels, at least 4.
+func (p *pathBuilder) steps(pts ...float64) int {
+	l := 0.0
+	for i := 0; i+3 < len(pts); i += 2 {
+		l += math.Hypot(pts[i+2]-pts[i], pts[i+3]-pts[i+1])
+	}
+	n := int(l*p.m.scale()/2) + 4
+	if n > 256 {
+		n = 256
+	}
+	return n
+}
+
+func (p *pathBuilder) cubicTo(x1, y1, x2, y2, x, y float64) {
+	x0, y0 := p.x, p.y
+	n := p.steps(x0, y0, x1, y1, x2, y2, x, y)
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		p.lineTo(u*u*u*x0+3*u*u*t*x1+3*u*t*t*x2+t*t*t*x,
+			u*u*u*y0+3*u*u*t*y1+3*u*t*t*y2+t*t*t*y)
+	}
+}
+
+func (p *pathBuilder) quadTo(x1, y1, x, y float64) {
+	x0, y0 := p.x, p.y
+	n := p.steps(x0, y0, x1, y1, x, y)
+	for i := 1; i <= n; i++ {
+		t := float64(i) / float64(n)
+		u := 1 - t
+		p.lineTo(u*u*x0+2*u*t*x1+t*t*x, u*u*y0+2*u*t*y1+t*t*y)
+	}
+}
+
+// arcTo draws an elliptical arc as the path A command does, converting its
+// endpoint form to centre form as in the SVG specification, appendix F.6.
+func (p *pathBuilder) arcTo(rx, ry, rotation float64, large, sweep bool, x, y float64) {
+	x0, y0 := p.x, p.y
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || x0 == x && y0 == y {
+		p.lineTo(x, y)
+		return
+	}
+	sin, cos := math.Sincos(rotation * math.Pi / 180)
+	dx, dy := (x0-x)/2, (y0-y)/2
+	x1, y1 := cos*dx+sin*dy, -sin*dx+cos*dy
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	k := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		k = -k
+	}
+	cx1, cy1 := k*rx*y1/ry, -k*ry*x1/rx
+	cx, cy := cos*cx1-sin*cy1+(x0+x)/2, sin*cx1+cos*cy1+(y0+y)/2
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+	n := int(math.Abs(delta)*math.Max(rx, ry)*p.m.scale()/2) + 4
+	if n > 256 {
+		n = 256
+	}
+	for i := 1; i < n; i++ {
+		s, c := math.Sincos(theta + delta*float64(i)/float64(n))
+		p.lineTo(cos*rx*c-sin*ry*s+cx, sin*rx*c+cos*ry*s+cy)
+	}
+	p.lineTo(x, y)
+}
+
+// parse follows the path data d, stopping at the first error as the SVG
+// specification asks.
+func (p *pathBuilder) parse(d string) {
+	s := pathScanner{s: d}
+	var cmd byte
+	// qx, qy is the last control point, for the smooth S and T commands.
+	var qx, qy float64
+	var prev byte
+	for {
+		if c, ok := s.command(); ok {
+			cmd = c
+		} else if cmd == 0 || s.done() {
+			break
+		}
+		rel := cmd >= 'a'
+		ox, oy := 0.0, 0.0
+		if rel {
+			ox, oy = p.x, p.y
+		}
+		switch cmd | 0x20 {
+		case 'z':
+			p.close()
+			// Z takes no arguments; another command must follow.
+			cmd = 0
+		case 'm':
+			x, y, ok := s.pair()
+			if !ok {
+				return
+			}
+			p.moveTo(ox+x, oy+y)
+			// Further pairs are implicit line-tos.
+			cmd = 'L' | cmd&0x20
+		case 'l':
+			x, y, ok := s.pair()
+			if !ok {
+				return
+			}
+			p.lineTo(ox+x, oy+y)
+		case 'h':
+			x, ok := s.number()
+			if !ok {
+				return
+			}
+			p.lineTo(ox+x, p.y)
+		case 'v':
+			y, ok := s.number()
+			if !ok {
+				return
+			}
+			p.lineTo(p.x, oy+y)
+		case 'c', 's':
+			var x1, y1 float64
+			if cmd|0x20 == 'c' {
+				var ok bool
+				if x1, y1, ok = s.pair(); !ok {
+					return
+				}
+				x1, y1 = ox+x1, oy+y1
+			} else if prev == 'c' || prev == 's' {
+				x1, y1 = 2*p.x-qx, 2*p.y-qy
+			} else {
+				x1, y1 = p.x, p.y
+			}
+			x2, y2, ok := s.pair()
+			x, y, ok2 := s.pair()
+			if !ok || !ok2 {
+				return
+			}
+			qx, qy = ox+x2, oy+y2
+			p.cubicTo(x1, y1, qx, qy, ox+x, oy+y)
+		case 'q', 't':
+			var x1, y1 float64
+			if cmd|0x20 == 'q' {
+				var ok bool
+				if x1, y1, ok = s.pair(); !ok {
+					return
+				}
+				x1, y1 = ox+x1, oy+y1
+			} else if prev == 'q' || prev == 't' {
+				x1, y1 = 2*p.x-qx, 2*p.y-qy
+			} else {
+				x1, y1 = p.x, p.y
+			}
+			x, y, ok := s.pair()
+			if !ok {
+				return
+			}
+			qx, qy = x1, y1
+			p.quadTo(x1, y1, ox+x, oy+y)
+		case 'a':
+			rx, ry, ok := s.pair()
+			rot, ok2 := s.number()
+			large, ok3 := s.flag()
+			sweep, ok4 := s.flag()
+			x, y, ok5 := s.pair()
+			if !ok || !ok2 || !ok3 || !ok4 || !ok5 {
+				return
+			}
+			p.arcTo(rx, ry, rot, large, sweep, ox+x, oy+y)
+		default:
+			return
+		}
+		prev = cmd | 0x20
+	}
+}
+
+// pathScanner tokenizes path data.
+type pathScanner struct {
+	s string
+	i int
+}
+
+func (s *pathScanner) skip() {
+	for s.i < len(s.s) && strings.IndexByte(" \t\r\n,", s.s[s.i]) >= 0 {
+		s.i++
+	}
+}
+
+func (s *pathScanner) done() bool {
+	s.skip()
+	return s.i >= len(s.s)
+}
+
+// command consumes a command letter, if one is next.
+func (s *pathScanner) command() (byte, bool) {
+	s.skip()
+	if s.i < len(s.s) && strings.IndexByte("MmZzLlHhVvCcSsQqTtAa", s.s[s.i]) >= 0 {
+		s.i++
+		return s.s[s.i-1], true
+	}
+	return 0, false
+}
+
+func (s *pathScanner) number() (float64, bool) {
+	s.skip()
+	j := s.i
+	if j < len(s.s) && (s.s[j] == '+' || s.s[j] == '-') {
+		j++
+	}
+	dot, digits := false, false
+	for ; j < len(s.s); j++ {
+		c := s.s[j]
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+			continue
+		case c == '.' && !dot:
+			dot = true
+			continue
+		}
+		break
+	}
+	if digits && j < len(s.s) && (s.s[j] == 'e' || s.s[j] == 'E') {
+		k := j + 1
+		if k < len(s.s) && (s.s[k] == '+' || s.s[k] == '-') {
+			k++
+		}
+		if k < len(s.s) && s.s[k] >= '0' && s.s[k] <= '9' {
+			for j = k; j < len(s.s) && s.s[j] >= '0' && s.s[j] <= '9'; j++ {
+			}
+		}
+	}
+	v, err := strconv.ParseFloat(s.s[s.i:j], 64)
+	if !digits || err != nil || math.IsInf(v, 0) {
+		return 0, false
+	}
+	s.i = j
+	return v, true
+}
+
+func (s *pathScanner) pair() (x, y float64, ok bool) {
+	if x, ok = s.number(); ok {
+		y, ok = s.number()
+	}
+	return
+}
+
+// flag reads an arc flag, a single 0 or 1 that need not be separated from
+// what follows.
+func (s *pathScanner) flag() (bool, bool) {
+	s.skip()
+	if s.i < len(s.s) && (s.s[s.i] == '0' || s.s[s.i] == '1') {
+		s.i++
+		return s.s[s.i-1] == '1', true
+	}
+	return false, false
+}
+
+// draw fills and strokes the contours of a shape with style st.
+func (r *svgRenderer) draw(contours []contour, st svgStyle) {
+	if !st.fill.none {
+		r.fill(contours, st.evenOdd, !st.fill.ink)
+	}
+	width := st.strokeWidth * st.m.scale()
+	if st.stroke.none || !(width > 0) {
+		return
+	}
+	on := !st.stroke.ink
+	if width <= 1 {
+		for _, c := range contours {
+			for i := 0; i < c.segments(); i++ {
+				p, q := c.pts[i], c.pts[(i+1)%len(c.pts)]
+				r.b.DrawLine(int(math.Floor(p.x)), int(math.Floor(p.y)), int(math.Floor(q.x)), int(math.Floor(q.y)), on)
+			}
+		}
+		return
+	}
+	// Each segment becomes a rectangle and each vertex a round join, all
+	// wound the same way so the non-zero rule fills their union.
+	// A half width of w+h, more than the canvas diagonal, already covers
+	// the canvas from any point on it, so wider strokes are clipped to it.
+	var parts [][]pt
+	hw := math.Min(width/2, r.w+r.h)
+	for _, c := range contours {
+		for i := 0; i < c.segments(); i++ {
+			p, q := c.pts[i], c.pts[(i+1)%len(c.pts)]
+			l := math.Hypot(q.x-p.x, q.y-p.y)
+			if l == 0 {
+				continue
+			}
+			nx, ny := -(q.y-p.y)/l*hw, (q.x-p.x)/l*hw
+			parts = append(parts, []pt{{p.x + nx, p.y + ny}, {q.x + nx, q.y + ny}, {q.x - nx, q.y - ny}, {p.x - nx, p.y - ny}})
+		}
+		for _, p := range c.pts {
+			parts = append(parts, disc(p, hw))
+		}
+	}
+	for _, part := range parts {
+		if area(part) < 0 {
+			for i, j := 0, len(part)-1; i < j; i, j = i+1, j-1 {
+				part[i], part[j] = part[j], part[i]
+			}
+		}
+	}
+	r.fillPolygons(parts, false, on)
+}
+
+// segments is the number of edges stroked: the closing edge only counts if
+// c is closed.
+func (c contour) segments() int {
+	if c.closed {
+		return len(c.pts)
+	}
+	return len(c.pts) - 1
+}
+
+// disc approximates a circle of radius rad around c, with one vertex per
+// pixel of radius up to 256.
+func disc(c pt, rad float64) []pt {
+	n := int(math.Min(rad, 248)) + 8
+	res := make([]pt, n)
+	for i := range res {
+		s, co := math.Sincos(2 * math.Pi * float64(i) / float64(n))
+		res[i] = pt{c.x + rad*co, c.y + rad*s}
+	}
+	return res
+}
+
+// area returns twice the signed area of the polygon.
+func area(p []pt) float64 {
+	a := 0.0
+	for i, v := range p {
+		w := p[(i+1)%len(p)]
+		a += v.x*w.y - w.x*v.y
+	}
+	return a
+}
+
+// fill sets the pixels whose centres lie inside contours, by the non-zero or
+// even-odd rule, to on.
+func (r *svgRenderer) fill(contours []contour, evenOdd, on bool) {
+	polys := make([][]pt, len(contours))
+	for i, c := range contours {
+		polys[i] = c.pts
+	}
+	r.fillPolygons(polys, evenOdd, on)
+}
+
+// fillPolygons is fill for bare polygons.
+func (r *svgRenderer) fillPolygons(contours [][]pt, evenOdd, on bool) {
+	b := r.b
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, c := range contours {
+		for _, p := range c {
+			minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+		}
+	}
+	y0 := int(math.Max(math.Floor(minY), float64(b.Rect.Min.Y)))
+	y1 := int(math.Min(math.Ceil(maxY), float64(b.Rect.Max.Y)))
+	type crossing struct {
+		x    float64
+		wind int
+	}
+	var xs []crossing
+	for y := y0; y < y1; y++ {
+		cy := float64(y) + 0.5
+		xs = xs[:0]
+		for _, c := range contours {
+			for i, p := range c {
+				q := c[(i+1)%len(c)]
+				if (p.y <= cy) == (q.y <= cy) {
+					continue
+				}
+				wind := 1
+				if q.y < p.y {
+					wind = -1
+				}
+				xs = append(xs, crossing{p.x + (cy-p.y)*(q.x-p.x)/(q.y-p.y), wind})
+			}
+		}
+		sort.Slice(xs, func(i, j int) bool { return xs[i].x < xs[j].x })
+		wind := 0
+		for i := 0; i+1 < len(xs); i++ {
+			wind += xs[i].wind
+			if wind == 0 || evenOdd && wind%2 == 0 {
+				continue
+			}
+			x0 := int(math.Max(math.Ceil(xs[i].x-0.5), float64(b.Rect.Min.X)))
+			x1 := int(math.Min(math.Ceil(xs[i+1].x-0.5), float64(b.Rect.Max.X)))
+			for x := x0; x < x1; x++ {
+				b.setBit(x, y, on)
+			}
+		}
+	}
+}
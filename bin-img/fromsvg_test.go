@@ -0,0 +1,152 @@
+package bin_img
+
+import (
+	"image"
+	"testing"
+)
+
+// svg16 wraps body in a 16x16 SVG document drawn 1:1 onto a 16x16 image.
+func svg16(body string) []byte {
+	return []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" viewBox="0 0 16 16">` + body + `</svg>`)
+}
+
+// checkSVG renders svg at 16x16 and checks that the pixels in ink are ink
+// and those in paper are paper.
+func checkSVG(t *testing.T, name string, svg []byte, ink, paper []image.Point) {
+	t.Helper()
+	b, err := FromSVG(svg, 16, 16)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	for _, p := range ink {
+		if b.IsWhite(p.X, p.Y) {
+			t.Errorf("%s: pixel %v is paper, want ink", name, p)
+		}
+	}
+	for _, p := range paper {
+		if b.IsBlack(p.X, p.Y) {
+			t.Errorf("%s: pixel %v is ink, want paper", name, p)
+		}
+	}
+}
+
+func TestFromSVGPathCommands(t *testing.T) {
+	square := struct{ ink, paper []image.Point }{
+		ink:   []image.Point{{2, 2}, {8, 8}, {13, 13}},
+		paper: []image.Point{{1, 1}, {14, 14}, {8, 15}},
+	}
+	for _, tt := range []struct {
+		name, d     string
+		ink, paper  []image.Point
+		squareShape bool
+	}{
+		{name: "absolute lines", d: "M2 2 L14 2 L14 14 L2 14 Z", squareShape: true},
+		{name: "relative lines", d: "m2 2 l12 0 l0 12 l-12 0 z", squareShape: true},
+		{name: "horizontal and vertical", d: "M2 2 H14 V14 H2 Z", squareShape: true},
+		{name: "relative horizontal and vertical", d: "m2 2 h12 v12 h-12 z", squareShape: true},
+		{name: "implicit lineto", d: "M2 2 14 2 14 14 2 14z", squareShape: true},
+		{name: "compact numbers", d: "M2,2L14,2,14,14,2,14Z", squareShape: true},
+		{name: "cubic", d: "M2 10 C2 0 14 0 14 10 Z",
+			ink: []image.Point{{8, 6}, {8, 9}}, paper: []image.Point{{8, 12}, {1, 3}, {14, 3}}},
+		// S reflects the control point (8,0) to (8,20), so the second
+		// hump bulges down.
+		{name: "smooth cubic", d: "M2 10 C2 0 8 0 8 10 S14 20 14 10 Z",
+			ink: []image.Point{{5, 7}, {11, 12}}, paper: []image.Point{{11, 7}, {5, 13}}},
+		{name: "quadratic", d: "M2 10 Q8 -2 14 10 Z",
+			ink: []image.Point{{8, 6}, {8, 9}}, paper: []image.Point{{8, 12}, {2, 3}}},
+		{name: "smooth quadratic", d: "M0 10 Q4 0 8 10 T16 10 Z",
+			ink: []image.Point{{4, 8}}, paper: []image.Point{{12, 8}}},
+		{name: "arc", d: "M2 8 A6 6 0 0 1 14 8 Z",
+			ink: []image.Point{{8, 4}, {8, 7}}, paper: []image.Point{{8, 10}, {2, 3}}},
+		{name: "arc sweep flipped", d: "M2 8 A6 6 0 0 0 14 8 Z",
+			ink: []image.Point{{8, 11}, {8, 9}}, paper: []image.Point{{8, 5}}},
+		{name: "relative arc", d: "m2 8 a6 6 0 0 1 12 0 z",
+			ink: []image.Point{{8, 4}}, paper: []image.Point{{8, 10}}},
+	} {
+		ink, paper := tt.ink, tt.paper
+		if tt.squareShape {
+			ink, paper = square.ink, square.paper
+		}
+		checkSVG(t, tt.name, svg16(`<path d="`+tt.d+`"/>`), ink, paper)
+	}
+}
+
+func TestFromSVGFillRules(t *testing.T) {
+	center, ring, outside := image.Pt(8, 8), image.Pt(3, 8), image.Pt(0, 0)
+	// In same both squares run clockwise; in opposite the inner one runs
+	// counter-clockwise.
+	same := "M2 2H14V14H2Z M6 6H10V10H6Z"
+	opposite := "M2 2H14V14H2Z M6 6V10H10V6Z"
+	for _, tt := range []struct {
+		name, d, rule string
+		centerInk     bool
+	}{
+		{"nonzero same direction", same, "nonzero", true},
+		{"nonzero opposite direction", opposite, "nonzero", false},
+		{"evenodd same direction", same, "evenodd", false},
+		{"evenodd opposite direction", opposite, "evenodd", false},
+	} {
+		ink, paper := []image.Point{ring}, []image.Point{outside}
+		if tt.centerInk {
+			ink = append(ink, center)
+		} else {
+			paper = append(paper, center)
+		}
+		checkSVG(t, tt.name, svg16(`<path fill-rule="`+tt.rule+`" d="`+tt.d+`"/>`), ink, paper)
+	}
+}
+
+func TestFromSVGTransforms(t *testing.T) {
+	for _, tt := range []struct {
+		name, body string
+		ink, paper []image.Point
+	}{
+		{"translate", `<rect x="0" y="0" width="4" height="4" transform="translate(8,8)"/>`,
+			[]image.Point{{8, 8}, {11, 11}}, []image.Point{{1, 1}, {12, 12}}},
+		{"scale", `<rect x="1" y="1" width="3" height="3" transform="scale(2)"/>`,
+			[]image.Point{{2, 2}, {7, 7}}, []image.Point{{1, 1}, {8, 8}}},
+		{"rotate about point", `<rect x="8" y="2" width="6" height="2" transform="rotate(90 8 8)"/>`,
+			[]image.Point{{12, 8}, {12, 13}}, []image.Point{{10, 3}, {12, 6}}},
+		{"matrix", `<rect x="0" y="0" width="4" height="4" transform="matrix(1 0 0 1 10 2)"/>`,
+			[]image.Point{{10, 2}, {13, 5}}, []image.Point{{9, 2}, {10, 6}}},
+		{"nested groups", `<g transform="translate(4,0)"><g transform="translate(0,4)"><rect width="4" height="4"/></g></g>`,
+			[]image.Point{{4, 4}, {7, 7}}, []image.Point{{3, 4}, {8, 8}}},
+	} {
+		checkSVG(t, tt.name, svg16(tt.body), tt.ink, tt.paper)
+	}
+
+	// A viewBox half the canvas size scales everything up by 2.
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 8"><rect x="2" y="2" width="4" height="4"/></svg>`)
+	checkSVG(t, "root viewBox", svg, []image.Point{{4, 4}, {11, 11}}, []image.Point{{3, 3}, {12, 12}})
+}
+
+func TestFromSVGStrokes(t *testing.T) {
+	checkSVG(t, "thin line", svg16(`<line x1="0" y1="8" x2="15" y2="8" stroke="black"/>`),
+		[]image.Point{{0, 8}, {15, 8}}, []image.Point{{8, 6}, {8, 10}})
+	checkSVG(t, "wide line", svg16(`<line x1="2" y1="8" x2="14" y2="8" stroke="black" stroke-width="4"/>`),
+		[]image.Point{{8, 6}, {8, 9}, {1, 8}}, []image.Point{{8, 4}, {8, 11}})
+	checkSVG(t, "open polyline", svg16(`<polyline points="2,2 14,2 14,14" fill="none" stroke="#000" stroke-width="2"/>`),
+		[]image.Point{{8, 2}, {14, 8}}, []image.Point{{2, 8}, {8, 14}, {8, 8}})
+	// Widths far beyond the canvas must neither panic nor allocate per
+	// pixel of radius.
+	for _, w := range []string{"1e30", "1e308", "1e400", "NaN", "Inf", "-5"} {
+		if _, err := FromSVG(svg16(`<line x1="2" y1="8" x2="14" y2="8" stroke="black" stroke-width="`+w+`"/>`), 16, 16); err != nil {
+			t.Errorf("stroke-width %s: %v", w, err)
+		}
+	}
+	checkSVG(t, "huge stroke", svg16(`<line x1="2" y1="8" x2="14" y2="8" stroke="black" stroke-width="1e30"/>`),
+		[]image.Point{{0, 0}, {15, 15}}, nil)
+}
+
+func TestFromSVGErrors(t *testing.T) {
+	for _, svg := range []string{
+		``,
+		`<html/>`,
+		`<svg><rect`,
+		`<svg xmlns="http://www.w3.org/2000/svg"><rect width="4" height="4" fill="url(#g)"/></svg>`,
+	} {
+		if _, err := FromSVG([]byte(svg), 16, 16); err == nil {
+			t.Errorf("FromSVG(%q) succeeded", svg)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package bin_img
+
+import (
+	"errors"
+	"image"
+)
+
+// bayer4 ranks the pixels of a 4x4 tile for ordered dithering.
+var bayer4 = [4][4]int{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// HalftonePlanes splits src into levels-1 planes that, printed on top of
+// each other, approximate levels shades of gray by ordered dithering. Each
+// pixel is assigned to one plane through a 4x4 Bayer pattern, and plane k
+// (counting from 1) inks its pixels whose quantized gray level is below k.
+// A black pixel is thus inked in its own plane only and a white one in none;
+// it takes the planes' union to ink all of a black area and a share of each
+// gray area matching its darkness.
+//
+// levels must be between 2 and 17, the number of shades a 4x4 pattern can
+// tell apart. Like NewBinary, src must be a multiple of 8 pixels wide. Ink is
+// off, as everywhere else in this package.
+func HalftonePlanes(src image.Image, levels int) ([]*Binary, error) {
+	if levels < 2 || levels > 17 {
+		return nil, errors.New("binimg: levels must be between 2 and 17")
+	}
+	bounds := src.Bounds()
+	n := levels - 1
+	planes := make([]*Binary, n)
+	for i := range planes {
+		p, err := NewBinary(bounds.Dx(), bounds.Dy())
+		if err != nil {
+			return nil, err
+		}
+		p.Fill(true)
+		planes[i] = p
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			q := int(luma8(src.At(bounds.Min.X+x, bounds.Min.Y+y))) * levels / 256
+			k := 1 + bayer4[y&3][x&3]*n/16
+			if q < k {
+				planes[k-1].setBit(x, y, false)
+			}
+		}
+	}
+	return planes, nil
+}
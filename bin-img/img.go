@@ -1,14 +1,29 @@
 package bin_img
 
 import (
+	"encoding/binary"
 	"errors"
+	"hash/fnv"
 	"image"
 	"image/color"
+	"io"
+	"math"
 	"math/bits"
 )
 
 // Binary is a 1bpp (bit-packed) image: 8 pixels per byte, MSB first.
 // A bit value of 1 means "on" (white = 255), 0 means "off" (black = 0).
+//
+// A Binary is safe for concurrent reads but not for reads concurrent with
+// writes, and views from SubImage share its pixels. To share an image that is
+// occasionally rebuilt, publish Snapshots through an atomic.Pointer: readers
+// load the current snapshot and never see it change, while the writer works
+// on its own copy and stores a new snapshot when done.
+//
+// Everything in this package that reads a color image takes each pixel's
+// color un-premultiplied, so partial transparency neither lightens nor
+// darkens it: a 50% opaque black prints as black. Fully transparent pixels
+// are bare paper, i.e. white and on.
 type Binary struct {
 	// Pix holds packed pixels, row-major. Each row is Stride bytes.
 	Pix    []byte
@@ -18,15 +33,38 @@ type Binary struct {
 
 // NewBinary creates a 1bpp image with the given width and height.
 // For simplicity and speed, width MUST be a multiple of 8.
+//
+// The pixel buffer, (w/8)*h bytes, must fit in an int. That is no practical
+// limit on 64-bit platforms; on 32-bit platforms it caps an image at 2 GiB,
+// e.g. 65536x262143 pixels, well beyond any printable label.
 func NewBinary(w, h int) (*Binary, error) {
-	if w <= 0 || h <= 0 {
-		return nil, errors.New("binimg: invalid dimensions")
+	if err := checkDimensions(w, h); err != nil {
+		return nil, err
 	}
-	if w%8 != 0 {
-		return nil, errors.New("binimg: width must be a multiple of 8")
+	stride := w / 8
+	pix := make([]byte, stride*h)
+	return &Binary{
+		Pix:    pix,
+		Stride: stride,
+		Rect:   image.Rect(0, 0, w, h),
+	}, nil
+}
+
+// NewBinaryLazy creates a 1bpp image with the given width and height whose
+// pixels are read straight from r, row-major and MSB first, exactly as they are
+// laid out in Pix. Exactly (w/8)*h bytes are read; a short read is an error.
+func NewBinaryLazy(r io.Reader, w, h int) (*Binary, error) {
+	if err := checkDimensions(w, h); err != nil {
+		return nil, err
 	}
 	stride := w / 8
 	pix := make([]byte, stride*h)
+	if _, err := io.ReadFull(r, pix); err != nil {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return nil, errors.New("binimg: short pixel data")
+		}
+		return nil, err
+	}
 	return &Binary{
 		Pix:    pix,
 		Stride: stride,
@@ -34,21 +72,27 @@ func NewBinary(w, h int) (*Binary, error) {
 	}, nil
 }
 
+func checkDimensions(w, h int) error {
+	if w <= 0 || h <= 0 {
+		return errors.New("binimg: invalid dimensions")
+	}
+	if w%8 != 0 {
+		return errors.New("binimg: width must be a multiple of 8")
+	}
+	if int64(h) > int64(math.MaxInt)/int64(w/8) {
+		return errors.New("binimg: image too large")
+	}
+	return nil
+}
+
 // Bounds implements image.Image.
 func (b *Binary) Bounds() image.Rectangle { return b.Rect }
 
 // BinaryModel implements image.Image.
 // We map bits to gray: 0 -> Gray{0}, 1 -> Gray{255}.
 var BinaryModel color.Model = color.ModelFunc(func(c color.Color) color.Color {
-	// Threshold at mid-gray. Alpha==0 -> off.
-	r, g, bl, a := c.RGBA()
-	if a == 0 {
-		return color.Gray{0}
-	}
-	// Luma-ish threshold on 16-bit range.
-	// (299, 587, 114) are standard coefficients scaled by 1000.
-	y := (299*r + 587*g + 114*bl) / 1000
-	if y >= 0x8000 {
+	// Threshold at mid-gray.
+	if luma16(c) >= 0x8000 {
 		return color.Gray{255}
 	}
 	return color.Gray{0}
@@ -103,10 +147,184 @@ func (b *Binary) SubImage(r image.Rectangle) image.Image {
 	}
 }
 
+// Subtract returns a new image with the pixels that are on in b but off in
+// other (b AND NOT other). Both images must have the same bounds; the result
+// has its origin at (0,0).
+func (b *Binary) Subtract(other *Binary) (*Binary, error) {
+	if b.Rect != other.Rect {
+		return nil, errors.New("binimg: bounds mismatch")
+	}
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	res := newBinary(w, h)
+	ra := make([]byte, res.Stride)
+	rb := make([]byte, res.Stride)
+	for y := 0; y < h; y++ {
+		ra = b.row(b.Rect.Min.Y+y, ra)
+		rb = other.row(other.Rect.Min.Y+y, rb)
+		out := res.Pix[y*res.Stride : (y+1)*res.Stride]
+		for i := range out {
+			out[i] = ra[i] &^ rb[i]
+		}
+	}
+	return res, nil
+}
+
+// Checksum returns a 64-bit FNV-1a hash of the image size and its visible
+// pixels. Stride, padding bits and the position of Rect do not contribute, so
+// images that look the same hash the same.
+func (b *Binary) Checksum() uint64 {
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	hash := fnv.New64a()
+	var size [16]byte
+	binary.BigEndian.PutUint64(size[:8], uint64(w))
+	binary.BigEndian.PutUint64(size[8:], uint64(h))
+	hash.Write(size[:])
+	row := make([]byte, (w+7)/8)
+	for y := b.Rect.Min.Y; y < b.Rect.Max.Y; y++ {
+		hash.Write(b.row(y, row))
+	}
+	return hash.Sum64()
+}
+
+// FingerprintExcluding is Checksum with the pixels inside the ignore
+// rectangles, in b's coordinates, hashed as off whatever their value. Labels
+// printed from one template then hash the same however their variable
+// regions, such as a serial number, differ. With no rectangles it equals
+// b.Checksum().
+func FingerprintExcluding(b *Binary, ignore []image.Rectangle) uint64 {
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	hash := fnv.New64a()
+	var size [16]byte
+	binary.BigEndian.PutUint64(size[:8], uint64(w))
+	binary.BigEndian.PutUint64(size[8:], uint64(h))
+	hash.Write(size[:])
+	row := make([]byte, (w+7)/8)
+	for y := b.Rect.Min.Y; y < b.Rect.Max.Y; y++ {
+		row = b.row(y, row)
+		for _, r := range ignore {
+			r = r.Intersect(b.Rect)
+			if y < r.Min.Y || y >= r.Max.Y {
+				continue
+			}
+			for x := r.Min.X - b.Rect.Min.X; x < r.Max.X-b.Rect.Min.X; x++ {
+				row[x>>3] &^= 0x80 >> uint(x&7)
+			}
+		}
+		hash.Write(row)
+	}
+	return hash.Sum64()
+}
+
+// OnCount returns the number of on pixels.
+func (b *Binary) OnCount() int {
+	n := 0
+	for _, c := range b.RowProjection() {
+		n += c
+	}
+	return n
+}
+
+// RowProjection returns the number of on pixels in each row, top to bottom.
+func (b *Binary) RowProjection() []int {
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	proj := make([]int, h)
+	row := make([]byte, (w+7)/8)
+	for y := 0; y < h; y++ {
+		for _, c := range b.row(b.Rect.Min.Y+y, row) {
+			proj[y] += bits.OnesCount8(c)
+		}
+	}
+	return proj
+}
+
+// ColProjection returns the number of on pixels in each column, left to
+// right.
+func (b *Binary) ColProjection() []int {
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	proj := make([]int, w)
+	row := make([]byte, (w+7)/8)
+	for y := 0; y < h; y++ {
+		for i, c := range b.row(b.Rect.Min.Y+y, row) {
+			for c != 0 {
+				lz := bits.LeadingZeros8(c)
+				proj[i*8+lz]++
+				c &^= 0x80 >> uint(lz)
+			}
+		}
+	}
+	return proj
+}
+
 // -------- Helpers --------
 
+// unpremultiply returns the 16-bit un-premultiplied color of c and whether c
+// is visible at all, i.e. not fully transparent.
+func unpremultiply(c color.Color) (r, g, b uint32, visible bool) {
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return 0, 0, 0, false
+	}
+	if a != 0xffff {
+		r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+	}
+	return r, g, b, true
+}
+
+// luma16 returns the 16-bit luma of c, following the transparency rule of
+// Binary.
+func luma16(c color.Color) uint32 {
+	r, g, bl, visible := unpremultiply(c)
+	if !visible {
+		return 0xffff
+	}
+	// (299, 587, 114) are standard coefficients scaled by 1000.
+	return (299*r + 587*g + 114*bl) / 1000
+}
+
+// luma8 is luma16 scaled to 8 bits.
+func luma8(c color.Color) uint8 {
+	return uint8(luma16(c) >> 8)
+}
+
+// newBinary allocates an image at the origin without NewBinary's width
+// restriction; the last byte of each row may carry padding bits.
+func newBinary(w, h int) *Binary {
+	stride := (w + 7) / 8
+	return &Binary{
+		Pix:    make([]byte, stride*h),
+		Stride: stride,
+		Rect:   image.Rect(0, 0, w, h),
+	}
+}
+
+// row packs the visible pixels of row y into dst, MSB first from the left
+// edge of Rect, with the padding bits of the last byte cleared. dst must hold
+// at least (Rect.Dx()+7)/8 bytes.
+func (b *Binary) row(y int, dst []byte) []byte {
+	w := b.Rect.Dx()
+	n := (w + 7) / 8
+	dst = dst[:n]
+	if b.Rect.Min.X&7 == 0 {
+		off := b.pixOffset(b.Rect.Min.X, y)
+		copy(dst, b.Pix[off:off+n])
+	} else {
+		for i := range dst {
+			dst[i] = 0
+		}
+		for x := 0; x < w; x++ {
+			if b.bit(b.Rect.Min.X+x, y) {
+				dst[x>>3] |= 0x80 >> uint(x&7)
+			}
+		}
+	}
+	if w&7 != 0 {
+		dst[n-1] &= byte(0xFF << uint(8-w&7))
+	}
+	return dst
+}
+
 func (b *Binary) pixOffset(x, y int) int {
-	return (y-b.Rect.Min.Y)*b.Stride + (x-b.Rect.Min.X)/8
+	return (y-b.Rect.Min.Y)*b.Stride + x/8 - b.Rect.Min.X/8
 }
 
 func (b *Binary) bit(x, y int) bool {
@@ -152,6 +370,10 @@ func FromGrayThreshold(src image.Image, thresh uint8) (*Binary, error) {
 // using the given 0..255 threshold (>= thresh => on).
 func (b *Binary) FromGrayThreshold(src image.Image, thresh uint8) {
 	bounds := b.Rect
+	if g, ok := src.(*image.Gray); ok && bounds.Min.X&7 == 0 && bounds.Dx()&7 == 0 && bounds.In(g.Rect) {
+		b.fromGray(g, thresh)
+		return
+	}
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		row := b.Pix[(y-bounds.Min.Y)*b.Stride : (y-bounds.Min.Y+1)*b.Stride]
 		// zero the row first
@@ -159,17 +381,8 @@ func (b *Binary) FromGrayThreshold(src image.Image, thresh uint8) {
 			row[i] = 0
 		}
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, bl, a := src.At(x, y).RGBA()
-			var on bool
-			if a == 0 {
-				on = false
-			} else {
-				// 8-bit luma-ish
-				y8 := uint8(((299*r + 587*g + 114*bl) / 1000) >> 8)
-				on = y8 >= thresh
-			}
-			if on {
-				i := (x - bounds.Min.X) >> 3
+			if luma8(src.At(x, y)) >= thresh {
+				i := x>>3 - bounds.Min.X>>3
 				bit := byte(0x80 >> (uint(x) & 7))
 				row[i] |= bit
 			}
@@ -177,6 +390,170 @@ func (b *Binary) FromGrayThreshold(src image.Image, thresh uint8) {
 	}
 }
 
+// fromGray is FromGrayThreshold for a Gray source covering b, with b's left
+// edge and width byte-aligned. It packs 8 pixels per output byte without
+// branching on the pixel values.
+func (b *Binary) fromGray(g *image.Gray, thresh uint8) {
+	bounds := b.Rect
+	t := uint32(thresh)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := b.Pix[(y-bounds.Min.Y)*b.Stride:][:bounds.Dx()/8]
+		src := g.Pix[g.PixOffset(bounds.Min.X, y):][:bounds.Dx()]
+		for i := range row {
+			p := src[i*8 : i*8+8 : i*8+8]
+			// ^(v-t)>>31 is 1 when v >= t: the subtraction wraps otherwise.
+			row[i] = byte((^(uint32(p[0])-t)>>31)<<7 |
+				(^(uint32(p[1])-t)>>31)<<6 |
+				(^(uint32(p[2])-t)>>31)<<5 |
+				(^(uint32(p[3])-t)>>31)<<4 |
+				(^(uint32(p[4])-t)>>31)<<3 |
+				(^(uint32(p[5])-t)>>31)<<2 |
+				(^(uint32(p[6])-t)>>31)<<1 |
+				^(uint32(p[7])-t)>>31)
+		}
+	}
+}
+
+// FromPaletted converts p by palette index rather than luma: pixels whose
+// index is listed in onIndices are on, all others are off.
+func FromPaletted(p *image.Paletted, onIndices []int) (*Binary, error) {
+	bounds := p.Bounds()
+	b, err := NewBinary(bounds.Dx(), bounds.Dy())
+	if err != nil {
+		return nil, err
+	}
+	var on [256]bool
+	for _, i := range onIndices {
+		if i >= 0 && i < len(on) {
+			on[i] = true
+		}
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		row := p.Pix[y*p.Stride : y*p.Stride+bounds.Dx()]
+		for x, idx := range row {
+			if on[idx] {
+				b.setBit(x, y, true)
+			}
+		}
+	}
+	return b, nil
+}
+
+// FromImageWithModel converts src by passing each pixel through model
+// instead of the built-in luma threshold, so callers can plug in their own
+// colour science, such as a Rec. 2020 luminance or a perceptual curve. The
+// converted colour is then read the way Set reads colours: on if its luma is
+// at least mid-gray, so a model need only return color.Gray{255} for paper
+// and color.Gray{0} for ink, as BinaryModel does.
+func FromImageWithModel(src image.Image, model color.Model) (*Binary, error) {
+	if model == nil {
+		return nil, errors.New("binimg: nil color model")
+	}
+	bounds := src.Bounds()
+	b, err := NewBinary(bounds.Dx(), bounds.Dy())
+	if err != nil {
+		return nil, err
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			c := model.Convert(src.At(bounds.Min.X+x, bounds.Min.Y+y))
+			if luma16(c) >= 0x8000 {
+				b.setBit(x, y, true)
+			}
+		}
+	}
+	return b, nil
+}
+
+// ExtractBitPlane returns the given bit of each pixel's 8-bit luma as a
+// binary image: plane 7 is the most significant bit, plane 0 the least.
+// Plane 7 equals FromGrayThreshold at 128; the lower planes carry ever finer
+// texture.
+func ExtractBitPlane(src image.Image, bitPlane uint) (*Binary, error) {
+	if bitPlane > 7 {
+		return nil, errors.New("binimg: bit plane must be 0..7")
+	}
+	bounds := src.Bounds()
+	b, err := NewBinary(bounds.Dx(), bounds.Dy())
+	if err != nil {
+		return nil, err
+	}
+	mask := uint8(1) << bitPlane
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			if luma8(src.At(bounds.Min.X+x, bounds.Min.Y+y))&mask != 0 {
+				b.setBit(x, y, true)
+			}
+		}
+	}
+	return b, nil
+}
+
+// SeparateCMYK splits src into cyan, magenta, yellow and black planes for
+// printing with one ribbon or pass per ink. Each plane follows the printing
+// convention: a pixel is off (printed) where its ink value is at least
+// thresh, and on elsewhere.
+func SeparateCMYK(src image.Image, thresh uint8) (c, m, y, k *Binary, err error) {
+	bounds := src.Bounds()
+	var planes [4]*Binary
+	for i := range planes {
+		if planes[i], err = NewBinary(bounds.Dx(), bounds.Dy()); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		planes[i].Fill(true)
+	}
+	for py := 0; py < bounds.Dy(); py++ {
+		for px := 0; px < bounds.Dx(); px++ {
+			r, g, bl, visible := unpremultiply(src.At(bounds.Min.X+px, bounds.Min.Y+py))
+			if !visible {
+				continue
+			}
+			ink := color.CMYKModel.Convert(color.RGBA64{uint16(r), uint16(g), uint16(bl), 0xffff}).(color.CMYK)
+			for i, v := range [4]uint8{ink.C, ink.M, ink.Y, ink.K} {
+				if v >= thresh {
+					planes[i].setBit(px, py, false)
+				}
+			}
+		}
+	}
+	return planes[0], planes[1], planes[2], planes[3], nil
+}
+
+// RedChannel is FromGrayThreshold on the red channel of src instead of its
+// luma: pixels whose 8-bit red value is at least thresh are on.
+func RedChannel(src image.Image, thresh uint8) (*Binary, error) {
+	return channelThreshold(src, thresh, 0)
+}
+
+// GreenChannel is RedChannel for the green channel.
+func GreenChannel(src image.Image, thresh uint8) (*Binary, error) {
+	return channelThreshold(src, thresh, 1)
+}
+
+// BlueChannel is RedChannel for the blue channel.
+func BlueChannel(src image.Image, thresh uint8) (*Binary, error) {
+	return channelThreshold(src, thresh, 2)
+}
+
+// channelThreshold thresholds channel ch of src: 0, 1 and 2 for red, green
+// and blue.
+func channelThreshold(src image.Image, thresh uint8, ch int) (*Binary, error) {
+	bounds := src.Bounds()
+	b, err := NewBinary(bounds.Dx(), bounds.Dy())
+	if err != nil {
+		return nil, err
+	}
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, bl, visible := unpremultiply(src.At(bounds.Min.X+x, bounds.Min.Y+y))
+			if !visible || uint8([3]uint32{r, g, bl}[ch]>>8) >= thresh {
+				b.setBit(x, y, true)
+			}
+		}
+	}
+	return b, nil
+}
+
 // BytesPerPixel is 0.125 for convenience (as a fraction).
 func (b *Binary) BytesPerPixel() float64 { return 0.125 }
 
@@ -266,19 +643,191 @@ func (b *Binary) Rotate180() {
 	}
 }
 
+// Downscale2x returns b at half size, at the origin, with each output pixel
+// covering a 2x2 block of b and rounding odd sizes up. A block with any ink,
+// an off pixel, gives ink, so one-pixel lines survive where a majority vote
+// would drop them.
+func (b *Binary) Downscale2x() (*Binary, error) {
+	return b.Downscale2xPolarity(PolarityOnWhite)
+}
+
+// Downscale2xPolarity is like Downscale2x but keeps the pixels that render
+// black under the given polarity, i.e. the on pixels for PolarityOnBlack.
+func (b *Binary) Downscale2xPolarity(pol Polarity) (*Binary, error) {
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("binimg: invalid dimensions")
+	}
+	// Work on the bits that render black, so that combining is an OR; for
+	// PolarityOnWhite the bits are inverted on the way in and out.
+	inv := byte(0)
+	if pol.white(true) {
+		inv = 0xFF
+	}
+	res := newBinary((w+1)/2, (h+1)/2)
+	n := (w + 7) / 8
+	// The rows get a spare zero byte so an odd n still reads byte pairs.
+	r0, r1 := make([]byte, n+1), make([]byte, n+1)
+	black := func(r []byte, y int) {
+		b.row(b.Rect.Min.Y+y, r)
+		for i := 0; i < n; i++ {
+			r[i] ^= inv
+		}
+		if w&7 != 0 {
+			r[n-1] &^= 0xFF >> (w & 7)
+		}
+	}
+	for y := 0; y < res.Rect.Dy(); y++ {
+		black(r0, 2*y)
+		if 2*y+1 < h {
+			black(r1, 2*y+1)
+		} else {
+			copy(r1, r0)
+		}
+		dst := res.Pix[y*res.Stride : (y+1)*res.Stride]
+		for i := range dst {
+			v := uint16(r0[2*i]|r1[2*i])<<8 | uint16(r0[2*i+1]|r1[2*i+1])
+			// OR each pair of columns into the pair's low bit, then gather
+			// those eight bits into one byte.
+			v = (v | v>>1) & 0x5555
+			v = (v | v>>1) & 0x3333
+			v = (v | v>>2) & 0x0F0F
+			v = (v | v>>4) & 0x00FF
+			dst[i] = byte(v) ^ inv
+		}
+	}
+	return res, nil
+}
+
+// WrapAround returns a copy of b, at the origin, cyclically shifted left by
+// offsetX pixels: columns pushed off the left edge come back in on the right,
+// so artwork for a label wrapped around a bottle can start anywhere. A
+// negative offset shifts right.
+//
+// An offset that is a multiple of 8 on a byte-aligned image, such as one
+// from NewBinary, moves whole bytes; any other offset goes pixel by pixel.
+func (b *Binary) WrapAround(offsetX int) *Binary {
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	res := newBinary(w, h)
+	if w == 0 {
+		return res
+	}
+	offsetX %= w
+	if offsetX < 0 {
+		offsetX += w
+	}
+	if offsetX&7 == 0 && w&7 == 0 && b.Rect.Min.X&7 == 0 {
+		n, k := w/8, offsetX/8
+		for y := 0; y < h; y++ {
+			src := b.Pix[y*b.Stride : y*b.Stride+n]
+			dst := res.Pix[y*res.Stride : y*res.Stride+n]
+			copy(dst, src[k:])
+			copy(dst[n-k:], src[:k])
+		}
+		return res
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if b.bit(b.Rect.Min.X+(x+offsetX)%w, b.Rect.Min.Y+y) {
+				res.setBit(x, y, true)
+			}
+		}
+	}
+	return res
+}
+
 // -------- Optional utilities --------
 
+// WriteRawTo writes the packed rows of b to w, MSB first from the left edge,
+// (Rect.Dx()+7)/8 bytes per row, with no header. It has the signature of
+// io.WriterTo's WriteTo. An image owning whole rows of Pix is written with a
+// single Write; a SubImage view is written row by row.
+func (b *Binary) WriteRawTo(w io.Writer) (int64, error) {
+	if b.Rect.Empty() {
+		return 0, nil
+	}
+	n := (b.Rect.Dx() + 7) / 8
+	if b.Rect.Min.X&7 == 0 && b.Rect.Dx() == b.Stride*8 {
+		m, err := w.Write(b.Pix[:b.Stride*b.Rect.Dy()])
+		return int64(m), err
+	}
+	var total int64
+	buf := make([]byte, n)
+	for y := b.Rect.Min.Y; y < b.Rect.Max.Y; y++ {
+		m, err := w.Write(b.row(y, buf))
+		total += int64(m)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Snapshot returns a deep copy of b with the same bounds, sharing no pixels
+// with it. Only the bytes holding b's own pixels are copied, so snapshotting
+// a small SubImage is cheap.
+func (b *Binary) Snapshot() *Binary {
+	s := &Binary{Stride: b.Stride, Rect: b.Rect}
+	if b.Rect.Empty() {
+		return s
+	}
+	n := (b.Rect.Dy()-1)*b.Stride + (b.Rect.Max.X-1)/8 - b.Rect.Min.X/8 + 1
+	s.Pix = make([]byte, n)
+	copy(s.Pix, b.Pix)
+	return s
+}
+
+// Inverted returns a read-only view of b with on and off swapped. The view
+// shares b's Pix, so later changes to b show through.
+func (b *Binary) Inverted() image.Image { return inverted{b} }
+
+type inverted struct{ b *Binary }
+
+func (v inverted) ColorModel() color.Model { return BinaryModel }
+
+func (v inverted) Bounds() image.Rectangle { return v.b.Rect }
+
+func (v inverted) At(x, y int) color.Color {
+	if !image.Pt(x, y).In(v.b.Rect) {
+		return color.Gray{0}
+	}
+	if v.b.bit(x, y) {
+		return color.Gray{0}
+	}
+	return color.Gray{255}
+}
+
+// Polarity selects how the export helpers render on and off pixels. It only
+// affects previews, never the bytes sent to a printer.
+type Polarity int
+
+const (
+	// PolarityOnWhite renders on pixels white and off pixels black, as At
+	// does.
+	PolarityOnWhite Polarity = iota
+	// PolarityOnBlack renders on pixels black and off pixels white.
+	PolarityOnBlack
+)
+
+// white reports whether a pixel with the given bit renders white.
+func (p Polarity) white(on bool) bool { return on != (p == PolarityOnBlack) }
+
 // ToPaletted makes a temporary 2-color paletted image (useful for PNG encoding).
 // Note: this allocates 1 byte per pixel (only for the exported image),
 // not for your in-memory working buffer.
 func (b *Binary) ToPaletted() *image.Paletted {
+	return b.ToPalettedPolarity(PolarityOnWhite)
+}
+
+// ToPalettedPolarity is like ToPaletted but renders with the given polarity.
+func (b *Binary) ToPalettedPolarity(pol Polarity) *image.Paletted {
 	p := image.NewPaletted(b.Rect, color.Palette{
 		color.Gray{0}, color.Gray{255},
 	})
 	bounds := b.Rect
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			if b.bit(x, y) {
+			if pol.white(b.bit(x, y)) {
 				p.SetColorIndex(x, y, 1)
 			} else {
 				p.SetColorIndex(x, y, 0)
@@ -287,3 +836,25 @@ func (b *Binary) ToPaletted() *image.Paletted {
 	}
 	return p
 }
+
+// ToRGBA renders b for compositing: off pixels, the ones a printer inks, in
+// inkColor and on pixels, the bare paper, in paperColor. With a transparent
+// paperColor only the ink shows when the result is drawn over a photo of the
+// product.
+func (b *Binary) ToRGBA(inkColor, paperColor color.Color) *image.RGBA {
+	ink := color.RGBAModel.Convert(inkColor).(color.RGBA)
+	paper := color.RGBAModel.Convert(paperColor).(color.RGBA)
+	res := image.NewRGBA(b.Rect)
+	bounds := b.Rect
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		i := res.PixOffset(bounds.Min.X, y)
+		for x := bounds.Min.X; x < bounds.Max.X; x, i = x+1, i+4 {
+			c := ink
+			if b.bit(x, y) {
+				c = paper
+			}
+			res.Pix[i], res.Pix[i+1], res.Pix[i+2], res.Pix[i+3] = c.R, c.G, c.B, c.A
+		}
+	}
+	return res
+}
@@ -0,0 +1,348 @@
+package bin_img
+
+import (
+	"image"
+	"image/color"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sameBinary reports whether a and b have the same bounds and pixels.
+func sameBinary(a, b *Binary) bool {
+	if a.Rect != b.Rect {
+		return false
+	}
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		for x := a.Rect.Min.X; x < a.Rect.Max.X; x++ {
+			if a.bit(x, y) != b.bit(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func mustBinary(t testing.TB, w, h int) *Binary {
+	t.Helper()
+	b, err := NewBinary(w, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSnapshotIsDeepCopy(t *testing.T) {
+	b := mustBinary(t, 32, 8)
+	for i := range b.Pix {
+		b.Pix[i] = byte(i * 29)
+	}
+	for _, r := range []image.Rectangle{b.Rect, image.Rect(3, 2, 21, 7), image.Rect(8, 0, 16, 1)} {
+		sub := b.SubImage(r).(*Binary)
+		s := sub.Snapshot()
+		if !sameBinary(s, sub) {
+			t.Fatalf("%v: snapshot differs", r)
+		}
+		sub.Fill(!sub.bit(r.Min.X, r.Min.Y))
+		if s.bit(r.Min.X, r.Min.Y) == sub.bit(r.Min.X, r.Min.Y) {
+			t.Fatalf("%v: snapshot shares pixels", r)
+		}
+	}
+}
+
+// TestSnapshotConcurrent runs the pattern documented on Binary: a writer
+// rebuilds its own copy and publishes Snapshots, readers load them. Run
+// with -race.
+func TestSnapshotConcurrent(t *testing.T) {
+	work := mustBinary(t, 64, 16)
+	var cur atomic.Pointer[Binary]
+	cur.Store(work.Snapshot())
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				s := cur.Load()
+				// Every version the writer publishes is uniform; a torn
+				// read would mix on and off pixels.
+				first := s.bit(0, 0)
+				for y := 0; y < s.Rect.Dy(); y++ {
+					for x := 0; x < s.Rect.Dx(); x++ {
+						if s.bit(x, y) != first {
+							t.Errorf("snapshot not uniform at (%d,%d)", x, y)
+							return
+						}
+					}
+				}
+			}
+		}()
+	}
+	for i, end := 0, time.Now().Add(50*time.Millisecond); time.Now().Before(end); i++ {
+		work.Fill(i%2 == 0)
+		cur.Store(work.Snapshot())
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestTransparency(t *testing.T) {
+	// A 50% opaque black logo on the right half of a transparent canvas.
+	src := image.NewNRGBA(image.Rect(0, 0, 16, 4))
+	for y := 0; y < 4; y++ {
+		for x := 8; x < 16; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{A: 128})
+		}
+	}
+	convert := map[string]func() (*Binary, error){
+		"FromGrayThreshold":  func() (*Binary, error) { return FromGrayThreshold(src, 128) },
+		"FromImageWithModel": func() (*Binary, error) { return FromImageWithModel(src, BinaryModel) },
+		"ExtractBitPlane":    func() (*Binary, error) { return ExtractBitPlane(src, 7) },
+		"ResizeBilinear":     func() (*Binary, error) { return ResizeBilinear(src, 16, 4, 128) },
+		"RedChannel":         func() (*Binary, error) { return RedChannel(src, 128) },
+		"HalftonePlanes": func() (*Binary, error) {
+			planes, err := HalftonePlanes(src, 2)
+			if err != nil {
+				return nil, err
+			}
+			return planes[0], nil
+		},
+		"Set": func() (*Binary, error) {
+			b := newBinary(16, 4)
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 16; x++ {
+					b.Set(x, y, src.At(x, y))
+				}
+			}
+			return b, nil
+		},
+	}
+	for name, f := range convert {
+		b, err := f()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 16; x++ {
+				if want := x < 8; b.IsWhite(x, y) != want {
+					t.Errorf("%s: pixel (%d,%d) on = %v, want %v", name, x, y, !want, want)
+				}
+			}
+		}
+	}
+
+	// Sobel sees the logo's edge, not the canvas's.
+	edges, err := SobelBinary(src, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 16; x++ {
+			if want := x != 7 && x != 8; edges.IsWhite(x, y) != want {
+				t.Errorf("SobelBinary: pixel (%d,%d) on = %v, want %v", x, y, !want, want)
+			}
+		}
+	}
+}
+
+// downscale2xRef is Downscale2x done pixel by pixel.
+func downscale2xRef(b *Binary) *Binary {
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	res := newBinary((w+1)/2, (h+1)/2)
+	for y := 0; y < res.Rect.Dy(); y++ {
+		for x := 0; x < res.Rect.Dx(); x++ {
+			on := true
+			for dy := 0; dy < 2 && 2*y+dy < h; dy++ {
+				for dx := 0; dx < 2 && 2*x+dx < w; dx++ {
+					on = on && b.bit(b.Rect.Min.X+2*x+dx, b.Rect.Min.Y+2*y+dy)
+				}
+			}
+			res.setBit(x, y, on)
+		}
+	}
+	return res
+}
+
+// noise returns a w x h image with pseudo-random pixels, mostly on.
+func noise(t testing.TB, w, h int) *Binary {
+	b := mustBinary(t, w, h)
+	seed := uint32(1)
+	for i := range b.Pix {
+		seed = seed*1664525 + 1013904223
+		b.Pix[i] = byte(seed>>24) | byte(seed>>16)
+	}
+	return b
+}
+
+func TestDownscale2xMatchesReference(t *testing.T) {
+	b := noise(t, 64, 40)
+	for _, r := range []image.Rectangle{b.Rect, image.Rect(3, 1, 40, 30), image.Rect(8, 0, 9, 1)} {
+		sub := b.SubImage(r).(*Binary)
+		got, err := sub.Downscale2x()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := downscale2xRef(sub); !sameBinary(got, want) {
+			t.Errorf("Downscale2x of %v differs from the per-pixel reference", r)
+		}
+	}
+}
+
+func BenchmarkDownscale2x(b *testing.B) {
+	// A 104 x 152 mm label at 8 dots per mm.
+	img := noise(b, 832, 1216)
+	b.Run("packed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			img.Downscale2x()
+		}
+	})
+	b.Run("per-pixel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			downscale2xRef(img)
+		}
+	})
+}
+
+// gradient returns a w x h Gray image whose pixels cycle through every level.
+func gradient(w, h int) *image.Gray {
+	g := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range g.Pix {
+		g.Pix[i] = byte(i * 7)
+	}
+	return g
+}
+
+func TestFromGrayThresholdFastPath(t *testing.T) {
+	g := gradient(64, 16)
+	fast, err := FromGrayThreshold(g, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Hiding the concrete type forces the per-pixel path.
+	slow, err := FromGrayThreshold(struct{ image.Image }{g}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameBinary(fast, slow) {
+		t.Fatal("Gray fast path differs from the per-pixel path")
+	}
+}
+
+func BenchmarkFromGrayThreshold(b *testing.B) {
+	g := gradient(832, 1216)
+	b.Run("gray", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			FromGrayThreshold(g, 128)
+		}
+	})
+	b.Run("per-pixel", func(b *testing.B) {
+		src := struct{ image.Image }{g}
+		for i := 0; i < b.N; i++ {
+			FromGrayThreshold(src, 128)
+		}
+	})
+}
+
+func TestChecksumIgnoresLayout(t *testing.T) {
+	b := noise(t, 64, 16).SubImage(image.Rect(0, 0, 61, 16)).(*Binary)
+	sum := b.Checksum()
+	if got := b.Snapshot().Checksum(); got != sum {
+		t.Errorf("Snapshot checksum %#x, want %#x", got, sum)
+	}
+
+	// The same pixels with a wider stride, padding bits set and the view
+	// starting mid-byte.
+	padded := &Binary{Pix: make([]byte, 10*16), Stride: 10, Rect: image.Rect(3, 0, 64, 16)}
+	for i := range padded.Pix {
+		padded.Pix[i] = 0xFF
+	}
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 61; x++ {
+			padded.setBit(x+3, y, b.bit(x, y))
+		}
+	}
+	if got := padded.Checksum(); got != sum {
+		t.Errorf("padded checksum %#x, want %#x", got, sum)
+	}
+
+	padded.setBit(40, 9, !padded.bit(40, 9))
+	if padded.Checksum() == sum {
+		t.Error("checksum unchanged after flipping a pixel")
+	}
+}
+
+func TestExtractBitPlaneGradient(t *testing.T) {
+	g := image.NewGray(image.Rect(0, 0, 256, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 256; x++ {
+			g.SetGray(x, y, color.Gray{uint8(x)})
+		}
+	}
+	for p := uint(0); p < 8; p++ {
+		b, err := ExtractBitPlane(g, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 256; x++ {
+				if want := x>>p&1 == 1; b.IsWhite(x, y) != want {
+					t.Fatalf("plane %d: pixel (%d,%d) on = %v, want %v", p, x, y, !want, want)
+				}
+			}
+		}
+		if p == 7 {
+			if th, _ := FromGrayThreshold(g, 128); !sameBinary(b, th) {
+				t.Error("plane 7 differs from FromGrayThreshold at 128")
+			}
+		}
+	}
+	if _, err := ExtractBitPlane(g, 8); err == nil {
+		t.Error("ExtractBitPlane accepted plane 8")
+	}
+}
+
+// A SubImage keeps its parent's Pix from the byte holding Min.X, so the
+// pixels of an unaligned one must be found by x/8 - Min.X/8, not by
+// (x-Min.X)/8.
+func TestUnalignedSubImageAddressing(t *testing.T) {
+	b, err := NewBinary(24, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 24; x += 3 {
+		b.SetOn(x, 1)
+	}
+	sub := b.SubImage(image.Rect(5, 1, 21, 2)).(*Binary)
+	for x := 5; x < 21; x++ {
+		if got, want := sub.IsWhite(x, 1), x%3 == 0; got != want {
+			t.Errorf("SubImage pixel (%d,1) is on = %v, want %v", x, got, want)
+		}
+	}
+	sub.SetOn(10, 1)
+	if !b.IsWhite(10, 1) || b.IsWhite(2, 1) {
+		t.Error("SetOn(10, 1) on the SubImage did not set pixel (10,1) of its parent")
+	}
+
+	src := image.NewGray(image.Rect(0, 0, 24, 2))
+	for x := 12; x < 24; x++ {
+		src.SetGray(x, 1, color.Gray{0xff})
+	}
+	dst, err := NewBinary(24, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst.SubImage(image.Rect(5, 1, 21, 2)).(*Binary).FromGrayThreshold(src, 128)
+	for x := 5; x < 21; x++ {
+		if got, want := dst.IsWhite(x, 1), x >= 12; got != want {
+			t.Errorf("FromGrayThreshold pixel (%d,1) is on = %v, want %v", x, got, want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package bin_img
+
+import (
+	"errors"
+	"image"
+)
+
+// ErodeRect erodes the on pixels with a kw x kh rectangle centred on each
+// pixel: a pixel stays on only if every pixel under the rectangle is on.
+// Pixels beyond the edges count as off.
+//
+// It runs in O(W*H) regardless of the rectangle size by testing each window
+// against a summed-area table of on pixels.
+func (b *Binary) ErodeRect(kw, kh int) (*Binary, error) {
+	if kw <= 0 || kh <= 0 {
+		return nil, errors.New("binimg: invalid kernel size")
+	}
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	// sum[(y+1)*(w+1)+(x+1)] counts the on pixels in [0,x]x[0,y].
+	sw := w + 1
+	sum := make([]int32, sw*(h+1))
+	for y := 0; y < h; y++ {
+		var rowSum int32
+		for x := 0; x < w; x++ {
+			if b.bit(b.Rect.Min.X+x, b.Rect.Min.Y+y) {
+				rowSum++
+			}
+			sum[(y+1)*sw+x+1] = sum[y*sw+x+1] + rowSum
+		}
+	}
+
+	res := newBinary(w, h)
+	full := int32(kw * kh)
+	for y := 0; y < h; y++ {
+		y0, y1 := y-kh/2, y-kh/2+kh
+		if y0 < 0 || y1 > h {
+			continue
+		}
+		for x := 0; x < w; x++ {
+			x0, x1 := x-kw/2, x-kw/2+kw
+			if x0 < 0 || x1 > w {
+				continue
+			}
+			n := sum[y1*sw+x1] - sum[y0*sw+x1] - sum[y1*sw+x0] + sum[y0*sw+x0]
+			if n == full {
+				res.setBit(x, y, true)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Thin reduces the on regions of b to skeletons one pixel wide with the
+// Zhang–Suen algorithm, keeping them connected. It repeatedly peels pixels
+// off the region borders, alternating between south-east and north-west
+// borders, until a pass removes nothing. The result has its origin at (0,0).
+func (b *Binary) Thin() *Binary {
+	return b.ThinPolarity(PolarityOnWhite)
+}
+
+// ThinPolarity is like Thin but thins the regions that render white under the
+// given polarity, i.e. the off pixels, such as ink strokes, for
+// PolarityOnBlack. The other pixels of the result keep the opposite value.
+func (b *Binary) ThinPolarity(pol Polarity) *Binary {
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	// px holds one byte per pixel with a one pixel border of background, so
+	// every pixel has eight neighbours.
+	sw := w + 2
+	px := make([]uint8, sw*(h+2))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if pol.white(b.bit(b.Rect.Min.X+x, b.Rect.Min.Y+y)) {
+				px[(y+1)*sw+x+1] = 1
+			}
+		}
+	}
+	// Offsets of the neighbours P2 to P9, clockwise from north.
+	nb := [8]int{-sw, -sw + 1, 1, sw + 1, sw, sw - 1, -1, -sw - 1}
+	var del []int
+	for changed := true; changed; {
+		changed = false
+		for step := 0; step < 2; step++ {
+			del = del[:0]
+			for y := 1; y <= h; y++ {
+				for i := y*sw + 1; i < y*sw+w+1; i++ {
+					if px[i] == 0 {
+						continue
+					}
+					var p [8]uint8
+					n, a := 0, 0
+					for k, d := range nb {
+						p[k] = px[i+d]
+						n += int(p[k])
+					}
+					for k := range p {
+						if p[k] == 0 && p[(k+1)%8] == 1 {
+							a++
+						}
+					}
+					if n < 2 || n > 6 || a != 1 {
+						continue
+					}
+					// p[0], p[2], p[4], p[6] are north, east, south, west.
+					if step == 0 && (p[0]*p[2]*p[4] != 0 || p[2]*p[4]*p[6] != 0) {
+						continue
+					}
+					if step == 1 && (p[0]*p[2]*p[6] != 0 || p[0]*p[4]*p[6] != 0) {
+						continue
+					}
+					del = append(del, i)
+				}
+			}
+			for _, i := range del {
+				px[i] = 0
+			}
+			changed = changed || len(del) > 0
+		}
+	}
+	res := newBinary(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (px[(y+1)*sw+x+1] == 1) == pol.white(true) {
+				res.setBit(x, y, true)
+			}
+		}
+	}
+	return res
+}
+
+// SobelBinary marks the edges of src: it applies the Sobel operator to the
+// luma of each pixel and prints, i.e. sets off, the pixels whose gradient
+// magnitude reaches thresh, leaving the rest on. The magnitude is scaled so
+// that a step between two flat areas measures the difference of their lumas
+// on the 0..255 scale. Pixels beyond the edges repeat the nearest edge pixel.
+func SobelBinary(src image.Image, thresh uint8) (*Binary, error) {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	b, err := NewBinary(w, h)
+	if err != nil {
+		return nil, err
+	}
+	luma := make([]float32, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			luma[y*w+x] = float32(luma8(src.At(bounds.Min.X+x, bounds.Min.Y+y)))
+		}
+	}
+	at := func(x, y int) float32 {
+		if x < 0 {
+			x = 0
+		} else if x >= w {
+			x = w - 1
+		}
+		if y < 0 {
+			y = 0
+		} else if y >= h {
+			y = h - 1
+		}
+		return luma[y*w+x]
+	}
+	// Compare squared magnitudes, with the kernels' weight of 4 folded in.
+	limit := 16 * float32(thresh) * float32(thresh)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) -
+				at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) -
+				at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+			b.setBit(x, y, gx*gx+gy*gy < limit)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,98 @@
+package bin_img
+
+import (
+	"fmt"
+	"image"
+	"testing"
+)
+
+// thickCross returns a 40x40 image whose pixels are fg in a cross with arms
+// 7 pixels thick and bg elsewhere.
+func thickCross(t *testing.T, fg bool) *Binary {
+	t.Helper()
+	b := mustBinary(t, 40, 40)
+	b.Fill(!fg)
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			if x >= 4 && x < 36 && y >= 4 && y < 36 && (x >= 17 && x < 24 || y >= 17 && y < 24) {
+				b.setBit(x, y, fg)
+			}
+		}
+	}
+	return b
+}
+
+// checkSkeleton checks that the pixels of b equal to fg form a single
+// 8-connected region one pixel wide, reaching into all four arms.
+func checkSkeleton(t *testing.T, b *Binary, fg bool) {
+	t.Helper()
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	is := func(x, y int) bool { return x >= 0 && y >= 0 && x < w && y < h && b.bit(x, y) == fg }
+	var start image.Point
+	n := 0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !is(x, y) {
+				continue
+			}
+			n++
+			start = image.Pt(x, y)
+			if is(x+1, y) && is(x, y+1) && is(x+1, y+1) {
+				t.Errorf("skeleton is two pixels wide at (%d,%d)", x, y)
+			}
+		}
+	}
+	if n == 0 {
+		t.Fatal("skeleton is empty")
+	}
+	seen := map[image.Point]bool{start: true}
+	for stack := []image.Point{start}; len(stack) > 0; {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				q := p.Add(image.Pt(dx, dy))
+				if is(q.X, q.Y) && !seen[q] {
+					seen[q] = true
+					stack = append(stack, q)
+				}
+			}
+		}
+	}
+	if len(seen) != n {
+		t.Errorf("skeleton has %d pixels but only %d are connected", n, len(seen))
+	}
+	for _, arm := range []image.Rectangle{
+		image.Rect(4, 17, 12, 24), image.Rect(28, 17, 36, 24),
+		image.Rect(17, 4, 24, 12), image.Rect(17, 28, 24, 36),
+	} {
+		found := false
+		for p := range seen {
+			found = found || p.In(arm)
+		}
+		if !found {
+			t.Errorf("skeleton does not reach into arm %v", arm)
+		}
+	}
+}
+
+func TestThinCross(t *testing.T) {
+	checkSkeleton(t, thickCross(t, true).Thin(), true)
+}
+
+func TestThinPolarityCross(t *testing.T) {
+	checkSkeleton(t, thickCross(t, false).ThinPolarity(PolarityOnBlack), false)
+}
+
+// BenchmarkErodeRect shows that the cost of ErodeRect does not grow with the
+// kernel size.
+func BenchmarkErodeRect(b *testing.B) {
+	img := noise(b, 832, 1216)
+	for _, k := range []int{3, 15, 63} {
+		b.Run(fmt.Sprintf("%dx%d", k, k), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				img.ErodeRect(k, k)
+			}
+		})
+	}
+}
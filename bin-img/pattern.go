@@ -0,0 +1,45 @@
+package bin_img
+
+import "errors"
+
+// NewStripes creates a test pattern of alternating black and white stripes,
+// each period pixels wide, starting with black at the top-left. Stripes run
+// left to right if horizontal, top to bottom otherwise.
+func NewStripes(w, h, period int, horizontal bool) (*Binary, error) {
+	if period <= 0 {
+		return nil, errors.New("binimg: invalid period")
+	}
+	return newPattern(w, h, func(x, y int) bool {
+		if horizontal {
+			return y/period%2 == 1
+		}
+		return x/period%2 == 1
+	})
+}
+
+// NewCheckerboard creates a test pattern of period x period squares,
+// alternating black and white, starting with black at the top-left.
+func NewCheckerboard(w, h, period int) (*Binary, error) {
+	if period <= 0 {
+		return nil, errors.New("binimg: invalid period")
+	}
+	return newPattern(w, h, func(x, y int) bool {
+		return (x/period+y/period)%2 == 1
+	})
+}
+
+// newPattern creates a w x h image whose pixel (x,y) is on if on(x,y).
+func newPattern(w, h int, on func(x, y int) bool) (*Binary, error) {
+	b, err := NewBinary(w, h)
+	if err != nil {
+		return nil, err
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if on(x, y) {
+				b.setBit(x, y, true)
+			}
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,56 @@
+package bin_img
+
+import (
+	"errors"
+	"image"
+)
+
+// ResizeBilinear scales src to newW x newH by bilinear interpolation of its
+// luma and thresholds the result at thresh (>= thresh => on), as
+// FromGrayThreshold does. Unlike nearest-neighbour scaling it keeps the bars
+// of a resized barcode evenly wide. As with NewBinary, newW must be a
+// multiple of 8.
+func ResizeBilinear(src image.Image, newW, newH int, thresh uint8) (*Binary, error) {
+	dst, err := NewBinary(newW, newH)
+	if err != nil {
+		return nil, err
+	}
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if sw <= 0 || sh <= 0 {
+		return nil, errors.New("binimg: empty source image")
+	}
+	luma := make([]float32, sw*sh)
+	for y := 0; y < sh; y++ {
+		for x := 0; x < sw; x++ {
+			luma[y*sw+x] = float32(luma16(src.At(bounds.Min.X+x, bounds.Min.Y+y))) / 257
+		}
+	}
+
+	// sample maps an output coordinate to the two source pixels around it,
+	// clamped to the image, and the weight of the second.
+	sample := func(d, srcLen, dstLen int) (i0, i1 int, f float32) {
+		s := (float32(d)+0.5)*float32(srcLen)/float32(dstLen) - 0.5
+		if s <= 0 {
+			return 0, 0, 0
+		}
+		i0 = int(s)
+		if i0 >= srcLen-1 {
+			return srcLen - 1, srcLen - 1, 0
+		}
+		return i0, i0 + 1, s - float32(i0)
+	}
+	t := float32(thresh)
+	for y := 0; y < newH; y++ {
+		y0, y1, fy := sample(y, sh, newH)
+		for x := 0; x < newW; x++ {
+			x0, x1, fx := sample(x, sw, newW)
+			top := luma[y0*sw+x0]*(1-fx) + luma[y0*sw+x1]*fx
+			bottom := luma[y1*sw+x0]*(1-fx) + luma[y1*sw+x1]*fx
+			if top*(1-fy)+bottom*fy >= t {
+				dst.setBit(x, y, true)
+			}
+		}
+	}
+	return dst, nil
+}
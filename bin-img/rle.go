@@ -0,0 +1,75 @@
+package bin_img
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+// ColumnRLE run-length encodes b column by column, each column top to bottom,
+// as one continuous stream of alternating off (black) and on (white) runs.
+// The first run is an off run and may be zero. Runs longer than
+// math.MaxInt16 are split by a zero-length run of the other colour.
+//
+// Vertical bars, as in barcodes, make long column runs, so this compresses
+// typical label artwork better than a row-major encoding.
+func (b *Binary) ColumnRLE() []int16 {
+	var runs []int16
+	on, run := false, 0
+	flush := func() {
+		for run > math.MaxInt16 {
+			runs = append(runs, math.MaxInt16, 0)
+			run -= math.MaxInt16
+		}
+		runs = append(runs, int16(run))
+	}
+	for x := b.Rect.Min.X; x < b.Rect.Max.X; x++ {
+		for y := b.Rect.Min.Y; y < b.Rect.Max.Y; y++ {
+			if b.bit(x, y) != on {
+				flush()
+				on, run = !on, 0
+			}
+			run++
+		}
+	}
+	flush()
+	return runs
+}
+
+// FromColumnRLE replaces b with a w x h image decoded from runs as produced
+// by ColumnRLE. As with NewBinary, w must be a multiple of 8. The runs must
+// cover exactly w*h pixels.
+func (b *Binary) FromColumnRLE(runs []int16, w, h int) error {
+	if err := checkDimensions(w, h); err != nil {
+		return err
+	}
+	if int64(w) > int64(math.MaxInt)/int64(h) {
+		return errors.New("binimg: image too large")
+	}
+	stride := w / 8
+	pix := make([]byte, stride*h)
+	total, i := w*h, 0
+	on := false
+	for _, r := range runs {
+		if r < 0 {
+			return errors.New("binimg: negative run length")
+		}
+		if int(r) > total-i {
+			return errors.New("binimg: runs exceed image size")
+		}
+		if on {
+			for end := i + int(r); i < end; i++ {
+				x, y := i/h, i%h
+				pix[y*stride+x/8] |= 0x80 >> uint(x&7)
+			}
+		} else {
+			i += int(r)
+		}
+		on = !on
+	}
+	if i != total {
+		return errors.New("binimg: runs do not cover the image")
+	}
+	b.Pix, b.Stride, b.Rect = pix, stride, image.Rect(0, 0, w, h)
+	return nil
+}
@@ -0,0 +1,85 @@
+package bin_img
+
+import "image"
+
+// Rotation is a clockwise rotation by a multiple of 90 degrees, as done by
+// the Rotate methods of Binary.
+type Rotation int
+
+const (
+	Rotation0 Rotation = iota
+	Rotation90
+	Rotation180
+	Rotation270
+)
+
+// Rotate90 returns a copy of b, at the origin, rotated 90 degrees clockwise,
+// e.g. to print an upright layout along a side label.
+func (b *Binary) Rotate90() *Binary {
+	return b.rotated(Rotation90)
+}
+
+// Rotate270 returns a copy of b, at the origin, rotated 90 degrees
+// counter-clockwise.
+func (b *Binary) Rotate270() *Binary {
+	return b.rotated(Rotation270)
+}
+
+func (b *Binary) rotated(r Rotation) *Binary {
+	t := Transform{Rotation: r, Src: b.Rect}
+	res := newBinary(t.Bounds().Dx(), t.Bounds().Dy())
+	for y := b.Rect.Min.Y; y < b.Rect.Max.Y; y++ {
+		for x := b.Rect.Min.X; x < b.Rect.Max.X; x++ {
+			if b.bit(x, y) {
+				p := t.Point(image.Pt(x, y))
+				res.setBit(p.X, p.Y, true)
+			}
+		}
+	}
+	return res
+}
+
+// Transform maps coordinates on an image with bounds Src to the image that
+// rotating it by Rotation gives: Rotate90 and Rotate270 return one at the
+// origin, while Rotate180, working in place, keeps Src. This finds positions
+// computed on an upright layout on the label rotated at the end, e.g. for a
+// click map over a preview.
+type Transform struct {
+	Rotation Rotation
+	Src      image.Rectangle
+}
+
+// Bounds returns the bounds of the rotated image.
+func (t Transform) Bounds() image.Rectangle {
+	switch t.Rotation & 3 {
+	case Rotation90, Rotation270:
+		return image.Rect(0, 0, t.Src.Dy(), t.Src.Dx())
+	}
+	return t.Src
+}
+
+// Point returns where the pixel at p ends up after the rotation.
+func (t Transform) Point(p image.Point) image.Point {
+	x, y := p.X-t.Src.Min.X, p.Y-t.Src.Min.Y
+	w, h := t.Src.Dx(), t.Src.Dy()
+	switch t.Rotation & 3 {
+	case Rotation90:
+		return image.Pt(h-1-y, x)
+	case Rotation180:
+		return image.Pt(t.Src.Min.X+w-1-x, t.Src.Min.Y+h-1-y)
+	case Rotation270:
+		return image.Pt(y, w-1-x)
+	}
+	return p
+}
+
+// Rect returns the area the pixels of r cover after the rotation.
+func (t Transform) Rect(r image.Rectangle) image.Rectangle {
+	if r.Empty() {
+		return image.Rectangle{}
+	}
+	p, q := t.Point(r.Min), t.Point(r.Max.Sub(image.Pt(1, 1)))
+	res := image.Rectangle{Min: p, Max: q}.Canon()
+	res.Max = res.Max.Add(image.Pt(1, 1))
+	return res
+}
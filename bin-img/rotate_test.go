@@ -0,0 +1,59 @@
+package bin_img
+
+import (
+	"image"
+	"testing"
+)
+
+func TestTransformMatchesRotation(t *testing.T) {
+	src := noise(t, 64, 40).SubImage(image.Rect(3, 2, 45, 31)).(*Binary)
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	for _, tt := range []struct {
+		r      Rotation
+		rotate func(*Binary) *Binary
+		// from returns the source pixel, relative to Src.Min, that lands at
+		// (x,y), relative to the rotated image's Min.
+		from func(x, y int) (int, int)
+	}{
+		{Rotation0, (*Binary).Snapshot,
+			func(x, y int) (int, int) { return x, y }},
+		{Rotation90, (*Binary).Rotate90,
+			func(x, y int) (int, int) { return y, h - 1 - x }},
+		{Rotation180, func(b *Binary) *Binary { s := b.Snapshot(); s.Rotate180(); return s },
+			func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }},
+		{Rotation270, (*Binary).Rotate270,
+			func(x, y int) (int, int) { return w - 1 - y, x }},
+	} {
+		tr := Transform{Rotation: tt.r, Src: src.Rect}
+		got := tt.rotate(src)
+		if tr.Bounds() != got.Rect {
+			t.Fatalf("rotation %d: Bounds() = %v, rotated image has %v", tt.r, tr.Bounds(), got.Rect)
+		}
+		min := got.Rect.Min
+		for y := 0; y < got.Rect.Dy(); y++ {
+			for x := 0; x < got.Rect.Dx(); x++ {
+				sx, sy := tt.from(x, y)
+				p := image.Pt(src.Rect.Min.X+sx, src.Rect.Min.Y+sy)
+				if got.bit(min.X+x, min.Y+y) != src.bit(p.X, p.Y) {
+					t.Fatalf("rotation %d: pixel (%d,%d) is not source pixel %v", tt.r, x, y, p)
+				}
+				if q := tr.Point(p); q != min.Add(image.Pt(x, y)) {
+					t.Fatalf("rotation %d: Point(%v) = %v, want %v", tt.r, p, q, min.Add(image.Pt(x, y)))
+				}
+			}
+		}
+
+		// Rect covers exactly the images of the pixels of r.
+		r := image.Rect(10, 5, 30, 12)
+		var want image.Rectangle
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				q := tr.Point(image.Pt(x, y))
+				want = want.Union(image.Rectangle{q, q.Add(image.Pt(1, 1))})
+			}
+		}
+		if got := tr.Rect(r); got != want {
+			t.Errorf("rotation %d: Rect(%v) = %v, want %v", tt.r, r, got, want)
+		}
+	}
+}
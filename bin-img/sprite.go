@@ -0,0 +1,61 @@
+package bin_img
+
+import (
+	"errors"
+	"image"
+)
+
+// SpriteSheet is a single Binary holding many small graphics, such as
+// checkmarks, icons or signatures, each addressed by name.
+type SpriteSheet struct {
+	sheet   *Binary
+	sprites map[string]image.Rectangle
+}
+
+// NewSpriteSheet creates an empty w x h sheet. As with NewBinary, w must be a
+// multiple of 8.
+func NewSpriteSheet(w, h int) (*SpriteSheet, error) {
+	sheet, err := NewBinary(w, h)
+	if err != nil {
+		return nil, err
+	}
+	return &SpriteSheet{sheet: sheet, sprites: make(map[string]image.Rectangle)}, nil
+}
+
+// Sheet returns the backing image, for drawing the sprites into.
+func (s *SpriteSheet) Sheet() *Binary { return s.sheet }
+
+// AddSprite names the region r of the sheet. r must be non-empty, lie within
+// the sheet and the name must not be taken.
+func (s *SpriteSheet) AddSprite(name string, r image.Rectangle) error {
+	if r.Empty() || !r.In(s.sheet.Rect) {
+		return errors.New("binimg: sprite outside sheet")
+	}
+	if _, ok := s.sprites[name]; ok {
+		return errors.New("binimg: duplicate sprite " + name)
+	}
+	s.sprites[name] = r
+	return nil
+}
+
+// Sprite returns a view of the named sprite sharing the sheet's pixels. Its
+// bounds are the sprite's region on the sheet.
+func (s *SpriteSheet) Sprite(name string) (*Binary, error) {
+	r, ok := s.sprites[name]
+	if !ok {
+		return nil, errors.New("binimg: unknown sprite " + name)
+	}
+	return s.sheet.SubImage(r).(*Binary), nil
+}
+
+// Blit copies the named sprite onto canvas with its top-left corner at (x,y),
+// overwriting both on and off pixels. Pixels falling outside canvas are
+// dropped.
+func (s *SpriteSheet) Blit(canvas *Binary, name string, x, y int) error {
+	r, ok := s.sprites[name]
+	if !ok {
+		return errors.New("binimg: unknown sprite " + name)
+	}
+	canvas.CopyFrom(s.sheet, x, y, r)
+	return nil
+}
@@ -0,0 +1,48 @@
+package bin_img
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSpriteBlit(t *testing.T) {
+	s, err := NewSpriteSheet(32, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(s.Sheet().Pix, noise(t, 32, 16).Pix)
+	r := image.Rect(5, 3, 18, 11)
+	if err := s.AddSprite("icon", r); err != nil {
+		t.Fatal(err)
+	}
+	sprite, err := s.Sprite("icon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sprite.Rect != r {
+		t.Fatalf("sprite bounds %v, want %v", sprite.Rect, r)
+	}
+	// Blit at an unaligned spot and partly off the canvas on the right and
+	// at the bottom.
+	for _, at := range []image.Point{{0, 0}, {3, 2}, {19, 10}, {-4, -2}} {
+		canvas := mustBinary(t, 24, 16)
+		canvas.Fill(true)
+		if err := s.Blit(canvas, "icon", at.X, at.Y); err != nil {
+			t.Fatal(err)
+		}
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 24; x++ {
+				want := true
+				if p := image.Pt(x, y).Sub(at).Add(r.Min); p.In(r) {
+					want = sprite.IsWhite(p.X, p.Y)
+				}
+				if canvas.IsWhite(x, y) != want {
+					t.Fatalf("Blit at %v: pixel (%d,%d) on = %v, want %v", at, x, y, !want, want)
+				}
+			}
+		}
+	}
+	if err := s.Blit(mustBinary(t, 8, 8), "missing", 0, 0); err == nil {
+		t.Error("Blit of an unknown sprite succeeded")
+	}
+}
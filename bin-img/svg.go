@@ -0,0 +1,185 @@
+package bin_img
+
+import (
+	"io"
+	"math/bits"
+	"strconv"
+)
+
+// Edge directions on the pixel-corner grid.
+const (
+	dirRight = iota
+	dirDown
+	dirLeft
+	dirUp
+)
+
+var dirDelta = [4][2]int{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+
+// ToSVGPath traces the outlines of the on (white) regions and writes them to w
+// as a single SVG <path d="..."/> element, in pixel units with the origin at
+// the top-left corner of Rect.
+//
+// Outlines follow pixel borders exactly. Outer borders run clockwise and
+// holes counter-clockwise, so the path renders correctly with the default
+// nonzero fill rule.
+func (b *Binary) ToSVGPath(w io.Writer) error {
+	return b.ToSVGPathPolarity(w, PolarityOnWhite)
+}
+
+// ToSVGPathPolarity is like ToSVGPath but traces the regions that render
+// white under the given polarity, i.e. the off pixels for PolarityOnBlack.
+func (b *Binary) ToSVGPathPolarity(w io.Writer, pol Polarity) error {
+	width, height := b.Rect.Dx(), b.Rect.Dy()
+	stride := width + 1
+	// out[v] holds a bit per direction for the unvisited edges leaving corner v.
+	out := make([]uint8, stride*(height+1))
+	on := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= width || y >= height {
+			return false
+		}
+		return pol.white(b.bit(b.Rect.Min.X+x, b.Rect.Min.Y+y))
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !on(x, y) {
+				continue
+			}
+			// Walk each exposed side with the pixel on the right hand.
+			if !on(x, y-1) {
+				out[y*stride+x] |= 1 << dirRight
+			}
+			if !on(x+1, y) {
+				out[y*stride+x+1] |= 1 << dirDown
+			}
+			if !on(x, y+1) {
+				out[(y+1)*stride+x+1] |= 1 << dirLeft
+			}
+			if !on(x-1, y) {
+				out[(y+1)*stride+x] |= 1 << dirUp
+			}
+		}
+	}
+
+	buf := []byte(`<path d="`)
+	for start := range out {
+		if out[start] == 0 {
+			continue
+		}
+		x, y := start%stride, start/stride
+		buf = appendPoint(append(buf, 'M'), x, y)
+		dir := -1
+		v := start
+		for {
+			next := nextDir(out[v], dir)
+			if next < 0 {
+				break
+			}
+			out[v] &^= 1 << next
+			if dir >= 0 && next != dir {
+				buf = appendPoint(append(buf, 'L'), x, y)
+			}
+			dir = next
+			x += dirDelta[dir][0]
+			y += dirDelta[dir][1]
+			v = y*stride + x
+			if v == start {
+				break
+			}
+		}
+		buf = append(buf, 'Z')
+	}
+	buf = append(buf, `"/>`...)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ToSVGRects writes the on (white) regions to w as SVG <rect/> elements, in
+// the same units as ToSVGPath. Each row is split into runs of on pixels and a
+// run is extended downwards for as long as the rows below have a run with
+// the same ends, so a solid block, such as an inverted header, becomes one
+// rect rather than one per row. This is simpler to post-process than the
+// outline ToSVGPath traces, though text and diagonal edges still take about
+// one rect per run.
+func (b *Binary) ToSVGRects(w io.Writer) error {
+	return b.ToSVGRectsPolarity(w, PolarityOnWhite)
+}
+
+// ToSVGRectsPolarity is like ToSVGRects but draws the regions that render
+// white under the given polarity, i.e. the off pixels for PolarityOnBlack.
+func (b *Binary) ToSVGRectsPolarity(w io.Writer, pol Polarity) error {
+	// run is a run of pixels [x0,x1) in every row from y down to the
+	// current one.
+	type run struct{ x0, x1, y int }
+	var buf []byte
+	emit := func(r run, y1 int) {
+		buf = append(buf, `<rect x="`...)
+		buf = strconv.AppendInt(buf, int64(r.x0), 10)
+		buf = append(buf, `" y="`...)
+		buf = strconv.AppendInt(buf, int64(r.y), 10)
+		buf = append(buf, `" width="`...)
+		buf = strconv.AppendInt(buf, int64(r.x1-r.x0), 10)
+		buf = append(buf, `" height="`...)
+		buf = strconv.AppendInt(buf, int64(y1-r.y), 10)
+		buf = append(buf, `"/>`...)
+	}
+	width, height := b.Rect.Dx(), b.Rect.Dy()
+	var open, next []run
+	for y := 0; y <= height; y++ {
+		next = next[:0]
+		// Both open and the runs of row y are sorted by x0, so a run is
+		// matched with an open one in a single merge-like pass.
+		i := 0
+		for x := 0; y < height && x < width; {
+			if !pol.white(b.bit(b.Rect.Min.X+x, b.Rect.Min.Y+y)) {
+				x++
+				continue
+			}
+			x0 := x
+			for x < width && pol.white(b.bit(b.Rect.Min.X+x, b.Rect.Min.Y+y)) {
+				x++
+			}
+			for i < len(open) && open[i].x0 < x0 {
+				emit(open[i], y)
+				i++
+			}
+			if i < len(open) && open[i].x0 == x0 && open[i].x1 == x {
+				next = append(next, open[i])
+				i++
+			} else {
+				next = append(next, run{x0, x, y})
+			}
+		}
+		for ; i < len(open); i++ {
+			emit(open[i], y)
+		}
+		open, next = next, open
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// nextDir picks the edge to follow from a corner. Where two outlines touch
+// diagonally the corner has two exits; turning right keeps the outlines
+// apart.
+func nextDir(exits uint8, dir int) int {
+	if exits == 0 {
+		return -1
+	}
+	if dir < 0 {
+		return bits.TrailingZeros8(exits)
+	}
+	for _, turn := range [...]int{1, 0, 3} {
+		d := (dir + turn) & 3
+		if exits&(1<<d) != 0 {
+			return d
+		}
+	}
+	return -1
+}
+
+func appendPoint(buf []byte, x, y int) []byte {
+	buf = strconv.AppendInt(buf, int64(x), 10)
+	buf = append(buf, ' ')
+	return strconv.AppendInt(buf, int64(y), 10)
+}
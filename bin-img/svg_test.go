@@ -0,0 +1,94 @@
+package bin_img
+
+import (
+	"bytes"
+	"image"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var rectRE = regexp.MustCompile(`<rect x="(\d+)" y="(\d+)" width="(\d+)" height="(\d+)"/>`)
+
+// svgRects returns the rects ToSVGRectsPolarity writes for b, checking that
+// they cover exactly the pixels that render white under pol, each once.
+func svgRects(t *testing.T, b *Binary, pol Polarity) []image.Rectangle {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := b.ToSVGRectsPolarity(&buf, pol); err != nil {
+		t.Fatal(err)
+	}
+	var rects []image.Rectangle
+	for _, m := range rectRE.FindAllStringSubmatch(buf.String(), -1) {
+		var v [4]int
+		for i := range v {
+			v[i], _ = strconv.Atoi(m[i+1])
+		}
+		rects = append(rects, image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]))
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("<rect")); n != len(rects) {
+		t.Fatalf("%d of %d <rect> elements are malformed", n-len(rects), n)
+	}
+	w, h := b.Rect.Dx(), b.Rect.Dy()
+	covered := make([]int, w*h)
+	for _, r := range rects {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				covered[y*w+x]++
+			}
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			want := 0
+			if pol.white(b.bit(b.Rect.Min.X+x, b.Rect.Min.Y+y)) {
+				want = 1
+			}
+			if covered[y*w+x] != want {
+				t.Fatalf("pixel (%d,%d) covered %d times, want %d", x, y, covered[y*w+x], want)
+			}
+		}
+	}
+	return rects
+}
+
+func TestToSVGRectsCount(t *testing.T) {
+	b := mustBinary(t, 384, 200)
+	if n := len(svgRects(t, b, PolarityOnWhite)); n != 0 {
+		t.Errorf("blank image: %d rects, want 0", n)
+	}
+	if n := len(svgRects(t, b, PolarityOnBlack)); n != 1 {
+		t.Errorf("blank image, inverted: %d rects, want 1", n)
+	}
+
+	// A solid header is one rect, and a disc one per change of its row span.
+	for y := 0; y < 60; y++ {
+		b.DrawLine(0, y, 383, y, true)
+	}
+	b.FillCircle(192, 130, 50, true)
+	want := 1
+	prev := [2]int{-1, -1}
+	for y := 60; y < 200; y++ {
+		span := [2]int{-1, -1}
+		for x := 0; x < 384; x++ {
+			if b.bit(x, y) {
+				if span[0] < 0 {
+					span[0] = x
+				}
+				span[1] = x
+			}
+		}
+		if span != prev && span[0] >= 0 {
+			want++
+		}
+		prev = span
+	}
+	if n := len(svgRects(t, b, PolarityOnWhite)); n != want {
+		t.Errorf("header and disc: %d rects, want %d", n, want)
+	}
+
+	// An unaligned view of the same art.
+	sub := b.SubImage(image.Rect(5, 3, 300, 190)).(*Binary)
+	svgRects(t, sub, PolarityOnWhite)
+	svgRects(t, sub, PolarityOnBlack)
+}
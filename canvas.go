@@ -0,0 +1,121 @@
+package tspl
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+// LabelCanvas is a drawing surface for continuous (gapless) media. Its width
+// is fixed while its height grows to fit whatever is placed on it, so
+// receipts and packing lists can be laid out before their length is known.
+type LabelCanvas struct {
+	bin *bin_img.Binary
+}
+
+// NewLabelCanvas creates an empty canvas width dots wide. width must be a
+// multiple of 8.
+func NewLabelCanvas(width int) (*LabelCanvas, error) {
+	bin, err := bin_img.NewBinary(width, 1)
+	if err != nil {
+		return nil, err
+	}
+	bin.Pix = bin.Pix[:0]
+	bin.Rect.Max.Y = 0
+	return &LabelCanvas{bin: bin}, nil
+}
+
+// Width returns the canvas width in dots.
+func (c *LabelCanvas) Width() int { return c.bin.Rect.Dx() }
+
+// Height returns the used height in dots, i.e. the bottom edge of the lowest
+// image placed so far.
+func (c *LabelCanvas) Height() int { return c.bin.Rect.Dy() }
+
+// Binary returns the canvas content. It is only valid until the next call to
+// Place, which may reallocate the pixels.
+func (c *LabelCanvas) Binary() *bin_img.Binary { return c.bin }
+
+// Place copies img onto the canvas with its top-left corner at (x,y), growing
+// the canvas downwards as needed. img must fit within the canvas width.
+func (c *LabelCanvas) Place(img *bin_img.Binary, x, y int) error {
+	b := img.Bounds()
+	if x < 0 || y < 0 {
+		return errors.New("placement must be at non-negative coordinates")
+	}
+	if x+b.Dx() > c.Width() {
+		return fmt.Errorf("image %d dots wide does not fit at x=%d on a %d dot wide canvas",
+			b.Dx(), x, c.Width())
+	}
+	c.grow(y + b.Dy())
+	c.bin.CopyFrom(img, x, y, b)
+	return nil
+}
+
+// GlyphFunc rasterizes a single character for PlaceVerticalText. It returns
+// the glyph image, which may be nil for blank characters such as spaces, and
+// the vertical advance to the next character in dots, taken from the font's
+// metrics.
+type GlyphFunc func(r rune) (glyph *bin_img.Binary, advance int, err error)
+
+// PlaceVerticalText stacks the characters of text top to bottom in a column
+// starting at (x,y), each upright and centred on the widest glyph, as on
+// vertically laid out East Asian labels. It returns the y just below the last
+// advance, where further text can continue.
+func (c *LabelCanvas) PlaceVerticalText(text string, x, y int, glyph GlyphFunc) (int, error) {
+	type placed struct {
+		img     *bin_img.Binary
+		advance int
+	}
+	var glyphs []placed
+	column := 0
+	for _, r := range text {
+		img, advance, err := glyph(r)
+		if err != nil {
+			return 0, err
+		}
+		if advance < 0 {
+			return 0, fmt.Errorf("negative advance %d for %q", advance, r)
+		}
+		if img != nil && img.Bounds().Dx() > column {
+			column = img.Bounds().Dx()
+		}
+		glyphs = append(glyphs, placed{img, advance})
+	}
+	for _, g := range glyphs {
+		if g.img != nil {
+			if err := c.Place(g.img, x+(column-g.img.Bounds().Dx())/2, y); err != nil {
+				return 0, err
+			}
+		}
+		y += g.advance
+	}
+	return y, nil
+}
+
+// grow extends the canvas to h rows. New rows are blank paper (on).
+func (c *LabelCanvas) grow(h int) {
+	if h <= c.Height() {
+		return
+	}
+	for len(c.bin.Pix) < h*c.bin.Stride {
+		c.bin.Pix = append(c.bin.Pix, 0xFF)
+	}
+	c.bin.Rect.Max.Y = h
+}
+
+// EncodeContinuous encodes the canvas with t as a gapless label: GAP 0,0 and
+// a SIZE whose height is the used height of the canvas. As the canvas grows
+// without bound, t's MaxHeightMM is what stops a runaway layout from feeding
+// a whole roll.
+func (c *LabelCanvas) EncodeContinuous(t *Driver, dpm int, opt Options) ([]byte, error) {
+	if c.Height() == 0 {
+		return nil, errors.New("canvas is empty")
+	}
+	_, bitmap, err := t.Image2Bytes(c.bin)
+	if err != nil {
+		return nil, err
+	}
+	return t.encodeWithBitmap(c.Width(), c.Height(), dpm, bitmap, opt, true)
+}
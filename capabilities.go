@@ -0,0 +1,81 @@
+package tspl
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnsupportedCommand is returned when a command is generated for a printer
+// model that lacks the feature.
+var ErrUnsupportedCommand = errors.New("command not supported by printer model")
+
+// PrinterCapabilities describes what a printer model can do. Figures are
+// nominal; options such as the cutter or peeler count as supported when the
+// model accepts them as an option.
+type PrinterCapabilities struct {
+	Model              string
+	DPI                int
+	MaxPrintWidthMM    float64
+	MaxSpeedInchPerSec float64
+	MaxDensity         int
+	SupportsCutter     bool
+	SupportsPeel       bool
+	SupportsQRCode     bool
+	SupportsPDF417     bool
+}
+
+// Supports reports whether the model accepts the named TSPL command, e.g.
+// "QRCODE" or "PDF417". Commands without a capability flag are assumed
+// supported.
+func (c *PrinterCapabilities) Supports(command string) bool {
+	switch strings.ToUpper(command) {
+	case "QRCODE":
+		return c.SupportsQRCode
+	case "PDF417":
+		return c.SupportsPDF417
+	case "CUT", string(SetCutter), string(SetPartialCutter), string(SetParticalCutter):
+		return c.SupportsCutter
+	case string(SetPeel):
+		return c.SupportsPeel
+	}
+	return true
+}
+
+var modelCapabilities = map[string]PrinterCapabilities{
+	"TTP-244 PRO": {DPI: 203, MaxPrintWidthMM: 104, MaxSpeedInchPerSec: 5, MaxDensity: 15,
+		SupportsCutter: true, SupportsPeel: true, SupportsQRCode: true, SupportsPDF417: true},
+	"TTP-247": {DPI: 203, MaxPrintWidthMM: 108, MaxSpeedInchPerSec: 7, MaxDensity: 15,
+		SupportsCutter: true, SupportsPeel: true, SupportsQRCode: true, SupportsPDF417: true},
+	"TTP-345": {DPI: 300, MaxPrintWidthMM: 106, MaxSpeedInchPerSec: 5, MaxDensity: 15,
+		SupportsCutter: true, SupportsPeel: true, SupportsQRCode: true, SupportsPDF417: true},
+	"TTP-346": {DPI: 300, MaxPrintWidthMM: 105.7, MaxSpeedInchPerSec: 6, MaxDensity: 15,
+		SupportsCutter: true, SupportsPeel: true, SupportsQRCode: true, SupportsPDF417: true},
+	"TDP-225": {DPI: 203, MaxPrintWidthMM: 54, MaxSpeedInchPerSec: 5, MaxDensity: 15,
+		SupportsCutter: true, SupportsPeel: true, SupportsQRCode: true, SupportsPDF417: true},
+	"DA210": {DPI: 203, MaxPrintWidthMM: 108, MaxSpeedInchPerSec: 6, MaxDensity: 15,
+		SupportsCutter: true, SupportsPeel: true, SupportsQRCode: true, SupportsPDF417: true},
+	"DA220": {DPI: 203, MaxPrintWidthMM: 108, MaxSpeedInchPerSec: 6, MaxDensity: 15,
+		SupportsCutter: true, SupportsPeel: true, SupportsQRCode: true, SupportsPDF417: true},
+	"TE200": {DPI: 203, MaxPrintWidthMM: 108, MaxSpeedInchPerSec: 6, MaxDensity: 15,
+		SupportsCutter: true, SupportsPeel: true, SupportsQRCode: true, SupportsPDF417: true},
+	"TE210": {DPI: 300, MaxPrintWidthMM: 108.4, MaxSpeedInchPerSec: 5, MaxDensity: 15,
+		SupportsCutter: true, SupportsPeel: true, SupportsQRCode: true, SupportsPDF417: true},
+	// The mobile models tear labels off by hand: they take neither a cutter
+	// nor a peeler.
+	"ALPHA-2R": {DPI: 203, MaxPrintWidthMM: 48, MaxSpeedInchPerSec: 4, MaxDensity: 15,
+		SupportsQRCode: true, SupportsPDF417: true},
+	"ALPHA-3R": {DPI: 203, MaxPrintWidthMM: 72, MaxSpeedInchPerSec: 4, MaxDensity: 15,
+		SupportsQRCode: true, SupportsPDF417: true},
+}
+
+// ModelCapabilities looks up a TSC model name, e.g. "TTP-247", in the built-in
+// table. The lookup ignores case and surrounding spaces.
+func ModelCapabilities(model string) (*PrinterCapabilities, bool) {
+	key := strings.ToUpper(strings.TrimSpace(model))
+	c, ok := modelCapabilities[key]
+	if !ok {
+		return nil, false
+	}
+	c.Model = key
+	return &c, true
+}
@@ -0,0 +1,54 @@
+package tspl
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestModelCapabilities(t *testing.T) {
+	c, ok := ModelCapabilities(" alpha-3r ")
+	if !ok {
+		t.Fatal("ALPHA-3R not found")
+	}
+	if c.Model != "ALPHA-3R" || c.SupportsCutter || c.SupportsPeel || !c.SupportsQRCode {
+		t.Errorf("ALPHA-3R = %+v", *c)
+	}
+	if _, ok := ModelCapabilities("TTP-000"); ok {
+		t.Error("found an unknown model")
+	}
+}
+
+func TestCapabilitiesReject(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	mobile, _ := ModelCapabilities("ALPHA-2R")
+	desktop, _ := ModelCapabilities("TTP-247")
+	noQR := *desktop
+	noQR.SupportsQRCode = false
+	speed, density := 5.0, 15
+	for _, tt := range []struct {
+		name string
+		c    *PrinterCapabilities
+		opt  Options
+		ok   bool
+	}{
+		{"cutter on mobile", mobile, Options{Cutter: true}, false},
+		{"peel on mobile", mobile, Options{Peel: true}, false},
+		{"wait for take on mobile", mobile, Options{WaitForTake: true}, false},
+		{"speed on mobile", mobile, Options{Speed: &speed}, false},
+		{"cut command on mobile", mobile, Options{Extra: []string{"CUT"}}, false},
+		{"plain label on mobile", mobile, Options{Density: &density}, true},
+		{"cutter on desktop", desktop, Options{Cutter: true, Peel: true, Speed: &speed}, true},
+		{"QR code without support", &noQR, Options{Extra: []string{`QRCODE 10,10,L,4,A,0,"x"`}}, false},
+		{"QR code with support", desktop, Options{Extra: []string{`QRCODE 10,10,L,4,A,0,"x"`}}, true},
+	} {
+		d := &Driver{Capabilities: tt.c}
+		_, err := d.Encode(16, 16, 8, img, tt.opt)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrUnsupportedCommand) {
+			t.Errorf("%s: Encode = %v, want ErrUnsupportedCommand", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package tspl
+
+import (
+	"context"
+	"fmt"
+	"image"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+// Image2BytesColumnMajor thresholds img like Image2Bytes but returns its
+// pixels column by column, for hosts driving heads that print a column at a
+// time: each column is packed top to bottom, 8 pixels per byte MSB first with
+// 1 for paper, and the columns follow each other left to right.
+//
+// TSPL itself has no column-major BITMAP mode; BITMAP's modes, the
+// BitmapMode constants, only choose how rows combine with the image buffer.
+// The data is therefore returned without a BITMAP header: sent to a TSPL
+// printer as a BITMAP it would print img transposed.
+func (t *Driver) Image2BytesColumnMajor(img image.Image) ([]byte, error) {
+	bwImg, flip, err := t.toBinary(context.Background(), img)
+	if err != nil {
+		return nil, err
+	}
+	bounds := bwImg.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	colBytes := (height + 7) / 8
+
+	data := make([]byte, colBytes*width)
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			if bwImg.IsWhite(bounds.Min.X+x, bounds.Min.Y+y) != flip {
+				data[x*colBytes+y/8] |= 1 << (7 - y%8)
+			}
+		}
+	}
+	return data, nil
+}
+
+// Bytes2ImageColumnMajor decodes the data of a width x height image returned
+// by Image2BytesColumnMajor back into an upright image, with bits on or off
+// according to InkIsOn as for Bytes2Image.
+func (t *Driver) Bytes2ImageColumnMajor(data []byte, width, height int) (*bin_img.Binary, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid image size %dx%d", width, height)
+	}
+	colBytes := (height + 7) / 8
+	if len(data)/colBytes != width || len(data)%colBytes != 0 {
+		return nil, fmt.Errorf("%d bytes of column data do not match a %dx%d image", len(data), width, height)
+	}
+	res, err := bin_img.NewBinary((width+7)/8*8, height)
+	if err != nil {
+		return nil, err
+	}
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			if (data[x*colBytes+y/8]>>(7-y%8)&1 == 1) != t.InkIsOn {
+				res.SetOn(x, y)
+			} else {
+				res.SetOff(x, y)
+			}
+		}
+	}
+	return res.SubImage(image.Rect(0, 0, width, height)).(*bin_img.Binary), nil
+}
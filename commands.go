@@ -0,0 +1,74 @@
+package tspl
+
+import "strings"
+
+// Keyword is a TSPL command name, or the name of a setting following SET.
+type Keyword string
+
+// Command names.
+const (
+	CmdAutoDetect Keyword = "AUTODETECT"
+	CmdBitmap     Keyword = "BITMAP"
+	CmdCls        Keyword = "CLS"
+	CmdDelay      Keyword = "DELAY"
+	CmdDensity    Keyword = "DENSITY"
+	CmdDownload   Keyword = "DOWNLOAD"
+	CmdFormFeed   Keyword = "FORMFEED"
+	CmdGap        Keyword = "GAP"
+	CmdGapDetect  Keyword = "GAPDETECT"
+	CmdOffset     Keyword = "OFFSET"
+	CmdOut        Keyword = "OUT"
+	CmdPrint      Keyword = "PRINT"
+	CmdPutBMP     Keyword = "PUTBMP"
+	CmdSet        Keyword = "SET"
+	CmdShift      Keyword = "SHIFT"
+	CmdSize       Keyword = "SIZE"
+	CmdSpeed      Keyword = "SPEED"
+)
+
+// Settings following SET. SetParticalCutter is the misspelling that older
+// firmwares require; see Dialect.
+const (
+	SetCutter         Keyword = "CUTTER"
+	SetHeadClose      Keyword = "HEAD_CLOSE"
+	SetPartialCutter  Keyword = "PARTIAL_CUTTER"
+	SetParticalCutter Keyword = "PARTICAL_CUTTER"
+	SetPeel           Keyword = "PEEL"
+)
+
+var keywords = map[Keyword]bool{
+	CmdAutoDetect: true, CmdBitmap: true, CmdCls: true, CmdDelay: true, CmdDensity: true,
+	CmdDownload: true, CmdFormFeed: true, CmdGap: true, CmdGapDetect: true, CmdOffset: true,
+	CmdOut: true, CmdPrint: true, CmdPutBMP: true, CmdSet: true, CmdShift: true,
+	CmdSize: true, CmdSpeed: true,
+	SetCutter: true, SetHeadClose: true, SetPartialCutter: true, SetParticalCutter: true, SetPeel: true,
+}
+
+// LookupKeyword returns the Keyword for name, ignoring case, and whether it
+// is one this package knows.
+func LookupKeyword(name string) (Keyword, bool) {
+	k := Keyword(strings.ToUpper(name))
+	return k, keywords[k]
+}
+
+// is reports whether name spells k, ignoring case.
+func (k Keyword) is(name []byte) bool {
+	return strings.EqualFold(string(k), string(name))
+}
+
+// passThrough lists the TSPL commands this package does not emit itself but
+// that programs, e.g. through Options.Extra, commonly add to a label.
+var passThrough = map[string]bool{
+	"AZTEC": true, "BAR": true, "BARCODE": true, "BLOCK": true, "BOX": true,
+	"CIRCLE": true, "CODEPAGE": true, "CUT": true, "DIAGONAL": true, "DMATRIX": true,
+	"ELLIPSE": true, "ERASE": true, "FEED": true, "BACKFEED": true, "HOME": true,
+	"MAXICODE": true, "PDF417": true, "PUTPCX": true, "QRCODE": true, "REVERSE": true,
+	"RSS": true, "SOUND": true, "TEXT": true, "TLC39": true,
+}
+
+// isCommand reports whether name, ignoring case, is a TSPL command this
+// package knows or passes through.
+func isCommand(name []byte) bool {
+	_, ok := LookupKeyword(string(name))
+	return ok || passThrough[strings.ToUpper(string(name))]
+}
@@ -0,0 +1,75 @@
+package tspl
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+// Patch replaces the bitmap data bytes starting at Offset with Data. Offset
+// counts from the end of the BITMAP header.
+type Patch struct {
+	Offset int
+	Data   []byte
+}
+
+// DeltaEncode returns the runs of BITMAP data bytes that differ between the
+// encodings of prev and next, which must be the same size. Applying them to
+// prev's BITMAP command with ApplyDelta gives next's, so consecutive labels
+// that differ only in a serial number need only a few bytes sent.
+func (t *Driver) DeltaEncode(prev, next *bin_img.Binary) ([]Patch, error) {
+	if prev.Bounds().Size() != next.Bounds().Size() {
+		return nil, errors.New("images differ in size")
+	}
+	headerSize, a, err := t.Image2Bytes(prev)
+	if err != nil {
+		return nil, err
+	}
+	_, b, err := t.Image2Bytes(next)
+	if err != nil {
+		return nil, err
+	}
+	a, b = a[headerSize:], b[headerSize:]
+
+	var patches []Patch
+	for i := 0; i < len(a); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		j := i + 1
+		for j < len(a) && a[j] != b[j] {
+			j++
+		}
+		patches = append(patches, Patch{Offset: i, Data: b[i:j]})
+		i = j
+	}
+	return patches, nil
+}
+
+// ApplyDelta returns a copy of the BITMAP command base with patches applied.
+// header may be nil, in which case it is parsed from base.
+func (t *Driver) ApplyDelta(base []byte, header *BitmapHeader, patches []Patch) ([]byte, error) {
+	if header == nil {
+		h, err := t.ParseBitmapHeader(base)
+		if err != nil {
+			return nil, err
+		}
+		header = h
+	}
+	if err := VerifyBitmapData(header, base); err != nil {
+		return nil, err
+	}
+	size := header.RowBytes * header.Height
+	for _, p := range patches {
+		if p.Offset < 0 || p.Offset > size || len(p.Data) > size-p.Offset {
+			return nil, fmt.Errorf("patch at %d+%d outside bitmap of %d bytes", p.Offset, len(p.Data), size)
+		}
+	}
+	res := make([]byte, len(base))
+	copy(res, base)
+	for _, p := range patches {
+		copy(res[header.HeaderEnd+p.Offset:], p.Data)
+	}
+	return res, nil
+}
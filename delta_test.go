@@ -0,0 +1,72 @@
+package tspl
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	sheet, err := bin_img.NewBinary(48, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range sheet.Pix {
+		sheet.Pix[i] = byte(i * 41)
+	}
+	// Both images are 3 bytes wide and 10 rows tall, so the data is 30
+	// bytes. next differs in the first and last pixel, making runs at the
+	// first and last byte, and in a run of whole bytes in the middle.
+	prev, err := bin_img.NewBinary(24, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev.CopyFrom(sheet, 0, 0, sheet.Rect)
+	next := sheet.SubImage(image.Rect(20, 6, 44, 16)).(*bin_img.Binary)
+	next.CopyFrom(prev, 20, 6, prev.Rect)
+	for _, p := range []image.Point{{20, 6}, {43, 15}} {
+		if next.IsWhite(p.X, p.Y) {
+			next.SetOff(p.X, p.Y)
+		} else {
+			next.SetOn(p.X, p.Y)
+		}
+	}
+	for x := 28; x < 44; x++ {
+		next.SetOn(x, 10)
+	}
+
+	patches, err := DefaultDriver.DeltaEncode(prev, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, last := patches[0], patches[len(patches)-1]
+	if first.Offset != 0 || last.Offset+len(last.Data) != 30 {
+		t.Errorf("patches %v do not cover the first and last byte", patches)
+	}
+	_, base, err := DefaultDriver.Image2Bytes(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, want, err := DefaultDriver.Image2Bytes(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DefaultDriver.ApplyDelta(base, nil, patches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("ApplyDelta gave %q, want %q", got, want)
+	}
+
+	for _, p := range []Patch{{Offset: 29, Data: []byte{1, 2}}, {Offset: -1, Data: []byte{1}}, {Offset: 31}} {
+		if _, err := DefaultDriver.ApplyDelta(base, nil, []Patch{p}); err == nil {
+			t.Errorf("ApplyDelta with patch at %d+%d succeeded", p.Offset, len(p.Data))
+		}
+	}
+	if _, err := DefaultDriver.DeltaEncode(prev, sheet); err == nil {
+		t.Error("DeltaEncode of images of different sizes succeeded")
+	}
+}
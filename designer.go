@@ -0,0 +1,169 @@
+package tspl
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+)
+
+// Designer builds a label from commands one at a time and keeps every
+// intermediate state, so an interactive editor can undo and redo changes.
+type Designer struct {
+	w, h, dpm int
+	drv       *Driver
+	// history[pos] is the current command list; later entries can be redone.
+	history [][]Command
+	pos     int
+}
+
+// NewDesigner creates an empty w x h dot label printed at dpm dots per
+// millimetre (8 if zero). It encodes with DefaultDriver unless WithDriver
+// sets another.
+func NewDesigner(w, h, dpm int) *Designer {
+	return &Designer{w: w, h: h, dpm: dpm, drv: DefaultDriver, history: [][]Command{nil}}
+}
+
+// WithDriver makes Bytes and Render check the label and options against t,
+// with its size limits, capabilities and bit polarity, instead of
+// d.drv. It returns d for chaining.
+func (d *Designer) WithDriver(t *Driver) *Designer {
+	d.drv = t
+	return d
+}
+
+// AddCommand appends cmd to the label. It discards any undone changes.
+func (d *Designer) AddCommand(cmd Command) {
+	cur := d.history[d.pos]
+	next := make([]Command, len(cur)+1)
+	copy(next, cur)
+	next[len(cur)] = cmd
+	d.history = append(d.history[:d.pos+1], next)
+	d.pos++
+}
+
+// Commands returns the current commands. The slice must not be modified.
+func (d *Designer) Commands() []Command { return d.history[d.pos] }
+
+// Undo reverts the last AddCommand or Redo and reports whether there was one.
+func (d *Designer) Undo() bool {
+	if d.pos == 0 {
+		return false
+	}
+	d.pos--
+	return true
+}
+
+// Redo reapplies the last undone change and reports whether there was one.
+func (d *Designer) Redo() bool {
+	if d.pos == len(d.history)-1 {
+		return false
+	}
+	d.pos++
+	return true
+}
+
+// Bytes encodes the label like Driver.Encode, with the current commands in
+// place of the bitmap. Like the Extra commands, they must be supported by the
+// driver's Capabilities, if set.
+func (d *Designer) Bytes(opt Options) ([]byte, error) {
+	if err := d.drv.checkOptions(d.w, d.h, d.dpm, opt); err != nil {
+		return nil, err
+	}
+	if c := d.drv.Capabilities; c != nil {
+		for _, cmd := range d.history[d.pos] {
+			if !c.Supports(cmd.Name) {
+				return nil, fmt.Errorf("%w: %s on %s", ErrUnsupportedCommand, cmd.Name, c.Model)
+			}
+		}
+	}
+	var body []byte
+	for _, cmd := range d.history[d.pos] {
+		if opt.MaxBitmapRows > 0 && CmdBitmap.is([]byte(cmd.Name)) {
+			split, err := d.drv.SplitBitmap(cmd.appendTo(nil), opt.MaxBitmapRows)
+			if err != nil {
+				return nil, err
+			}
+			body = append(body, split...)
+			continue
+		}
+		body = cmd.appendTo(body)
+	}
+	return d.drv.assemble(d.w, d.h, d.dpm, body, opt, false), nil
+}
+
+// Render previews the label in black and white. Only CLS and BITMAP are
+// drawn; the printer renders text, barcodes and shapes with its own fonts
+// and they are left out. opt is validated as for Bytes.
+func (d *Designer) Render(opt Options) (*image.NRGBA, error) {
+	if err := d.drv.checkOptions(d.w, d.h, d.dpm, opt); err != nil {
+		return nil, err
+	}
+	if d.w <= 0 || d.h <= 0 {
+		return nil, fmt.Errorf("invalid label size %dx%d", d.w, d.h)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, d.w, d.h))
+	cls := func() {
+		for i := range img.Pix {
+			img.Pix[i] = 0xFF
+		}
+	}
+	cls()
+	for _, cmd := range d.history[d.pos] {
+		switch {
+		case CmdCls.is([]byte(cmd.Name)):
+			cls()
+		case CmdBitmap.is([]byte(cmd.Name)):
+			if err := renderBitmap(img, cmd); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return img, nil
+}
+
+// renderBitmap draws a BITMAP command onto img. A 0 bit is a printed dot.
+func renderBitmap(img *image.NRGBA, cmd Command) error {
+	if len(cmd.Args) != 5 {
+		return fmt.Errorf("invalid BITMAP arguments %q", cmd.Args)
+	}
+	var v [5]int
+	for i, a := range cmd.Args {
+		n, err := strconv.Atoi(a)
+		if err != nil {
+			return fmt.Errorf("invalid BITMAP arguments %q", cmd.Args)
+		}
+		v[i] = n
+	}
+	x0, y0, rowBytes, height, mode := v[0], v[1], v[2], v[3], v[4]
+	if rowBytes <= 0 || height <= 0 || len(cmd.Data)/rowBytes < height {
+		return errors.New("BITMAP data does not match its size")
+	}
+	if mode < BitmapModeOverwrite || mode > BitmapModeXOR {
+		return fmt.Errorf("unsupported BITMAP compression mode %d", mode)
+	}
+	black, white := color.NRGBA{A: 0xFF}, color.NRGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	for y := 0; y < height; y++ {
+		for x := 0; x < rowBytes*8; x++ {
+			p := image.Pt(x0+x, y0+y)
+			if !p.In(img.Rect) {
+				continue
+			}
+			ink := cmd.Data[y*rowBytes+x/8]>>(7-uint(x%8))&1 == 0
+			switch {
+			case mode == BitmapModeOverwrite && !ink:
+				img.SetNRGBA(p.X, p.Y, white)
+			case mode == BitmapModeXOR && ink:
+				if img.NRGBAAt(p.X, p.Y) == black {
+					img.SetNRGBA(p.X, p.Y, white)
+				} else {
+					img.SetNRGBA(p.X, p.Y, black)
+				}
+			case ink:
+				img.SetNRGBA(p.X, p.Y, black)
+			}
+		}
+	}
+	return nil
+}
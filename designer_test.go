@@ -0,0 +1,97 @@
+package tspl
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+func TestDesignerUsesDriver(t *testing.T) {
+	mobile, _ := ModelCapabilities("ALPHA-2R")
+	for _, tt := range []struct {
+		name string
+		drv  *Driver
+		opt  Options
+	}{
+		{"width limit", &Driver{MaxWidthMM: 50}, Options{}},
+		{"height limit", &Driver{MaxHeightMM: 20}, Options{}},
+		{"capabilities", &Driver{Capabilities: mobile}, Options{Cutter: true}},
+	} {
+		// 480x240 dots at 8 dots/mm is a 60x30 mm label.
+		d := NewDesigner(480, 240, 8)
+		d.AddCommand(Command{Name: "BOX", Args: []string{"0", "0", "100", "100", "2"}})
+		if _, err := d.Bytes(tt.opt); err != nil {
+			t.Fatalf("%s: Bytes with DefaultDriver: %v", tt.name, err)
+		}
+		d.WithDriver(tt.drv)
+		if _, err := d.Bytes(tt.opt); err == nil {
+			t.Errorf("%s: Bytes succeeded", tt.name)
+		}
+		if _, err := d.Render(tt.opt); err == nil {
+			t.Errorf("%s: Render succeeded", tt.name)
+		}
+	}
+
+	d := NewDesigner(480, 240, 8).WithDriver(&Driver{Capabilities: mobile})
+	d.AddCommand(Command{Name: "CUT"})
+	if _, err := d.Bytes(Options{}); !errors.Is(err, ErrUnsupportedCommand) {
+		t.Errorf("Bytes with CUT on %s = %v, want ErrUnsupportedCommand", mobile.Model, err)
+	}
+}
+
+func TestEncodeContinuousUsesDriver(t *testing.T) {
+	c, err := NewLabelCanvas(64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := bin_img.NewBinary(64, 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Place(img, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	// 400 dots at 8 dots/mm is a 50 mm label.
+	if _, err := c.EncodeContinuous(DefaultDriver, 8, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.EncodeContinuous(&Driver{MaxHeightMM: 40}, 8, Options{}); err == nil {
+		t.Error("EncodeContinuous of a 50 mm canvas with a 40 mm limit succeeded")
+	}
+	mobile, _ := ModelCapabilities("ALPHA-2R")
+	if _, err := c.EncodeContinuous(&Driver{Capabilities: mobile}, 8, Options{Cutter: true}); !errors.Is(err, ErrUnsupportedCommand) {
+		t.Errorf("EncodeContinuous with CUTTER on %s = %v, want ErrUnsupportedCommand", mobile.Model, err)
+	}
+	// InkIsOn flips the bits of the canvas: all off is all paper.
+	doc, err := c.EncodeContinuous(&Driver{InkIsOn: true}, 8, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img2, err := DefaultDriver.Bytes2Image(doc[bytes.Index(doc, []byte(CmdBitmap)):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !img2.Bitmap.IsWhite(0, 0) {
+		t.Error("EncodeContinuous ignored InkIsOn")
+	}
+}
+
+func TestHeaderStrict(t *testing.T) {
+	d := &Driver{MaxWidthMM: 50}
+	if _, err := d.HeaderStrict(480, 240, 8, Options{}); err == nil {
+		t.Error("HeaderStrict of a 60 mm wide label with a 50 mm limit succeeded")
+	}
+	off := 30.0
+	if _, err := d.HeaderStrict(320, 240, 8, Options{Offset: &off}); err == nil {
+		t.Error("HeaderStrict with an out of range offset succeeded")
+	}
+	h, err := d.HeaderStrict(320, 240, 8, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := d.Header(320, 240, 8, Options{}); h != want {
+		t.Errorf("HeaderStrict = %q, want %q", h, want)
+	}
+}
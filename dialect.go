@@ -0,0 +1,59 @@
+package tspl
+
+import "fmt"
+
+// Dialect identifies a family of TSPL firmwares that spell some commands
+// differently.
+type Dialect int
+
+const (
+	// DialectTSPL is the original TSPL spelling, including the PARTICAL_CUTTER
+	// misspelling that several TSC firmwares require.
+	DialectTSPL Dialect = iota
+	// DialectTSPL2 is the TSPL2 spelling, which uses PARTIAL_CUTTER.
+	DialectTSPL2
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case DialectTSPL:
+		return "TSPL"
+	case DialectTSPL2:
+		return "TSPL2"
+	}
+	return fmt.Sprintf("Dialect(%d)", int(d))
+}
+
+func (d Dialect) partialCutter() Keyword {
+	if d == DialectTSPL2 {
+		return SetPartialCutter
+	}
+	return SetParticalCutter
+}
+
+// DetectDialect inspects the command spellings used in program and returns
+// the best-guess dialect. Programs that give no hint are reported as
+// DialectTSPL, the spelling Header emits by default.
+//
+// BITMAP payloads are skipped by length, so binary data never matches.
+func DetectDialect(program []byte) Dialect {
+	var tspl, tspl2 int
+	_ = scanCommands(program, func(cmd []byte) bool {
+		name, args := splitWord(cmd)
+		if !CmdSet.is(name) {
+			return true
+		}
+		key, _ := splitWord(args)
+		switch {
+		case SetParticalCutter.is(key):
+			tspl++
+		case SetPartialCutter.is(key):
+			tspl2++
+		}
+		return true
+	})
+	if tspl2 > tspl {
+		return DialectTSPL2
+	}
+	return DialectTSPL
+}
@@ -0,0 +1,82 @@
+package tspl
+
+import (
+	"image"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+// DirtyBitmap is an encoded BITMAP command that overlays are applied to in
+// place. It remembers the area changed since the last ResetDirty, so an
+// editor or transport can re-send only the changed part instead of the whole
+// bitmap.
+type DirtyBitmap struct {
+	driver *Driver
+	header *BitmapHeader
+	body   []byte
+	dirty  image.Rectangle
+}
+
+// ByteRange is a half-open range [Start, End) of byte offsets.
+type ByteRange struct {
+	Start, End int
+}
+
+// NewDirtyBitmap wraps a copy of bitmapCmd, a BITMAP command as returned by
+// Image2Bytes.
+func (t *Driver) NewDirtyBitmap(bitmapCmd []byte) (*DirtyBitmap, error) {
+	h, err := t.ParseBitmapHeader(bitmapCmd)
+	if err != nil {
+		return nil, err
+	}
+	body := make([]byte, len(bitmapCmd))
+	copy(body, bitmapCmd)
+	return &DirtyBitmap{driver: t, header: h, body: body}, nil
+}
+
+// Overlay writes overlay at (xOff,yOff) like Driver.OverlayBinary, but in
+// place, and marks the covered area dirty.
+func (d *DirtyBitmap) Overlay(overlay *bin_img.Binary, xOff, yOff int) error {
+	if _, err := d.driver.checkOverlay(d.header, d.body, overlay, xOff, yOff); err != nil {
+		return err
+	}
+	overlayInto(d.body, d.header, overlay, xOff, yOff, d.driver.InkIsOn)
+	r := image.Rect(xOff, yOff, xOff+overlay.Bounds().Dx(), yOff+overlay.Bounds().Dy())
+	d.dirty = d.dirty.Union(r)
+	return nil
+}
+
+// Bytes returns the current BITMAP command. It aliases the internal buffer
+// and changes with later overlays.
+func (d *DirtyBitmap) Bytes() []byte { return d.body }
+
+// Header returns the parsed header of the bitmap.
+func (d *DirtyBitmap) Header() *BitmapHeader { return d.header }
+
+// Dirty returns the smallest rectangle, in pixels, covering every overlay
+// since the last ResetDirty. It is empty if nothing changed.
+func (d *DirtyBitmap) Dirty() image.Rectangle { return d.dirty }
+
+// DirtyRanges returns the byte ranges of Bytes that hold the dirty area, one
+// per row, merged where rows are contiguous.
+func (d *DirtyBitmap) DirtyRanges() []ByteRange {
+	if d.dirty.Empty() {
+		return nil
+	}
+	h := d.header
+	x0, x1 := d.dirty.Min.X/8, (d.dirty.Max.X+7)/8
+	var ranges []ByteRange
+	for y := d.dirty.Min.Y; y < d.dirty.Max.Y; y++ {
+		start := h.HeaderEnd + y*h.RowBytes
+		r := ByteRange{start + x0, start + x1}
+		if n := len(ranges); n > 0 && ranges[n-1].End == r.Start {
+			ranges[n-1].End = r.End
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// ResetDirty clears the dirty area, e.g. after the changes were sent.
+func (d *DirtyBitmap) ResetDirty() { d.dirty = image.Rectangle{} }
@@ -0,0 +1,79 @@
+package tspl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// dumpBytesPerLine is how many bytes DumpHex shows per line.
+const dumpBytesPerLine = 16
+
+// DumpHex returns an annotated hex dump of a TSPL program for diagnosing what
+// a printer actually received. Each part is introduced by a comment line
+// giving its kind, offset and length: text for ordinary commands, and the
+// header and payload of each BITMAP. Text lines show ASCII beside the hex;
+// BITMAP payloads are shown as hex only.
+func DumpHex(program []byte) string {
+	var b strings.Builder
+	section := func(kind string, start, end int, ascii bool) {
+		if start == end {
+			return
+		}
+		fmt.Fprintf(&b, "; %s, %d bytes at 0x%08x\n", kind, end-start, start)
+		for off := start; off < end; off += dumpBytesPerLine {
+			line := program[off:end]
+			if len(line) > dumpBytesPerLine {
+				line = line[:dumpBytesPerLine]
+			}
+			fmt.Fprintf(&b, "%08x ", off)
+			for i := 0; i < dumpBytesPerLine; i++ {
+				if i < len(line) {
+					fmt.Fprintf(&b, " %02x", line[i])
+				} else if ascii {
+					b.WriteString("   ")
+				}
+			}
+			if ascii {
+				b.WriteString("  |")
+				for _, c := range line {
+					if c < 0x20 || c > 0x7e {
+						c = '.'
+					}
+					b.WriteByte(c)
+				}
+				b.WriteByte('|')
+			}
+			b.WriteByte('\n')
+		}
+	}
+
+	textStart, pos := 0, 0
+	for pos < len(program) {
+		advance, token, err := splitCommand(program[pos:], true)
+		start := pos
+		for start < len(program) && isSpace(program[start]) {
+			start++
+		}
+		if bytes.HasPrefix(token, []byte(CmdBitmap)) || err != nil {
+			if h, herr := parseBitmapHeader(program[start:]); herr == nil {
+				end := start + len(token)
+				kind := "BITMAP payload"
+				if err != nil {
+					end, kind = len(program), "BITMAP payload (truncated)"
+				}
+				section("text", textStart, start, true)
+				section("BITMAP header", start, start+h.HeaderEnd, true)
+				section(kind, start+h.HeaderEnd, end, false)
+				textStart, pos = end, end
+				continue
+			}
+		}
+		if err != nil {
+			break
+		}
+		pos += advance
+	}
+	section("text", textStart, len(program), true)
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package tspl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tsplEscaper maps the characters that cannot appear literally in a TSPL
+// string argument to their escape sequences.
+var tsplEscaper = strings.NewReplacer(
+	`"`, `\["]`,
+	"\r", `\[R]`,
+	"\n", `\[L]`,
+)
+
+// EscapeTSPL escapes s for use inside a double-quoted TSPL string argument,
+// such as the content of TEXT or BARCODE: double quotes become \["], carriage
+// returns \[R] and line feeds \[L]. Without it a quote would end the argument
+// early and a line break would end the command.
+func EscapeTSPL(s string) string {
+	return tsplEscaper.Replace(s)
+}
+
+// UnescapeTSPL reverses EscapeTSPL. Other \[...] sequences are kept as is,
+// since TSPL prints them literally. A bare double quote or line break is an
+// error: it cannot occur in an escaped argument.
+func UnescapeTSPL(s string) (string, error) {
+	rest := strings.ReplaceAll(s, `\["]`, "")
+	if i := strings.IndexAny(rest, "\"\r\n"); i >= 0 {
+		return "", fmt.Errorf("unescaped %q in TSPL string", rest[i])
+	}
+	return tsplUnescaper.Replace(s), nil
+}
+
+var tsplUnescaper = strings.NewReplacer(
+	`\["]`, `"`,
+	`\[R]`, "\r",
+	`\[L]`, "\n",
+)
@@ -0,0 +1,80 @@
+// Package flatbuf serialises binary images in a fixed little-endian layout
+// whose fields can be read in place, in the spirit of FlatBuffers but without
+// a schema or generated code:
+//
+//	offset 0   4 bytes  magic "TSBF"
+//	offset 4   4 bytes  width in pixels
+//	offset 8   4 bytes  height in pixels
+//	offset 12  ...      packed rows, (width+7)/8 bytes each, MSB first
+package flatbuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+const (
+	magic      = "TSBF"
+	headerSize = 12
+)
+
+// EncodeFlatBuf serialises b.
+func EncodeFlatBuf(b *bin_img.Binary) []byte {
+	r := b.Bounds()
+	w, h := r.Dx(), r.Dy()
+	rowBytes := (w + 7) / 8
+	data := make([]byte, headerSize+rowBytes*h)
+	copy(data, magic)
+	binary.LittleEndian.PutUint32(data[4:], uint32(w))
+	binary.LittleEndian.PutUint32(data[8:], uint32(h))
+	pix := data[headerSize:]
+	if r.Min.X%8 == 0 && w%8 == 0 {
+		// Pix starts at the byte holding Rect.Min.X, as SubImage leaves it.
+		for y := 0; y < h; y++ {
+			copy(pix[y*rowBytes:(y+1)*rowBytes], b.Pix[y*b.Stride:])
+		}
+		return data
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if b.IsWhite(r.Min.X+x, r.Min.Y+y) {
+				pix[y*rowBytes+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return data
+}
+
+// DecodeFlatBuf returns the image serialised in data. The image shares data's
+// memory, so data must not be modified while the image is in use.
+func DecodeFlatBuf(data []byte) (*bin_img.Binary, error) {
+	w, h, err := Size(data)
+	if err != nil {
+		return nil, err
+	}
+	if w <= 0 || h <= 0 {
+		return nil, errors.New("flatbuf: invalid dimensions")
+	}
+	stride := (w + 7) / 8
+	if h > (len(data)-headerSize)/stride {
+		return nil, errors.New("flatbuf: data too short")
+	}
+	return &bin_img.Binary{
+		Pix:    data[headerSize : headerSize+stride*h : headerSize+stride*h],
+		Stride: stride,
+		Rect:   image.Rect(0, 0, w, h),
+	}, nil
+}
+
+// Size reads the width and height from data without decoding the pixels.
+func Size(data []byte) (w, h int, err error) {
+	if len(data) < headerSize || string(data[:4]) != magic {
+		return 0, 0, errors.New("flatbuf: not an image")
+	}
+	w = int(binary.LittleEndian.Uint32(data[4:]))
+	h = int(binary.LittleEndian.Uint32(data[8:]))
+	return w, h, nil
+}
@@ -0,0 +1,111 @@
+package flatbuf
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"image"
+	"testing"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+func testImage(t testing.TB, w, h int) *bin_img.Binary {
+	t.Helper()
+	b, err := bin_img.NewBinary(w, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range b.Pix {
+		b.Pix[i] = byte(i*37 + 11)
+	}
+	return b
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := testImage(t, 32, 5)
+	for _, r := range []image.Rectangle{
+		src.Rect,
+		image.Rect(8, 0, 16, 2),
+		image.Rect(16, 1, 32, 5),
+		image.Rect(3, 1, 29, 4),
+		image.Rect(8, 2, 13, 5),
+	} {
+		sub := src.SubImage(r).(*bin_img.Binary)
+		got, err := DecodeFlatBuf(EncodeFlatBuf(sub))
+		if err != nil {
+			t.Fatalf("%v: %v", r, err)
+		}
+		if got.Bounds() != image.Rect(0, 0, r.Dx(), r.Dy()) {
+			t.Fatalf("%v: bounds %v", r, got.Bounds())
+		}
+		for y := 0; y < r.Dy(); y++ {
+			for x := 0; x < r.Dx(); x++ {
+				if got.IsWhite(x, y) != src.IsWhite(r.Min.X+x, r.Min.Y+y) {
+					t.Fatalf("%v: pixel (%d,%d) differs", r, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestSubImageByte(t *testing.T) {
+	src := testImage(t, 32, 2)
+	src.Pix[1], src.Pix[2] = 0xAA, 0x55
+	data := EncodeFlatBuf(src.SubImage(image.Rect(8, 0, 16, 2)).(*bin_img.Binary))
+	if got := data[headerSize]; got != 0xAA {
+		t.Fatalf("row 0 = %#x, want 0xaa", got)
+	}
+}
+
+func TestDecodeShort(t *testing.T) {
+	data := EncodeFlatBuf(testImage(t, 16, 4))
+	for _, d := range [][]byte{nil, data[:headerSize], data[:len(data)-1], []byte("XXXX00000000")} {
+		if _, err := DecodeFlatBuf(d); err == nil {
+			t.Errorf("DecodeFlatBuf(%d bytes) succeeded", len(d))
+		}
+	}
+}
+
+// The benchmarks compare a round trip through flatbuf with JSON and gob
+// encodings of the same Binary; run with -benchmem to see the allocations.
+
+func BenchmarkFlatBuf(b *testing.B) {
+	img := testImage(b, 832, 1200)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := DecodeFlatBuf(EncodeFlatBuf(img)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJSON(b *testing.B) {
+	img := testImage(b, 832, 1200)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, err := json.Marshal(img)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var got bin_img.Binary
+		if err := json.Unmarshal(data, &got); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGob(b *testing.B) {
+	img := testImage(b, 832, 1200)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(img); err != nil {
+			b.Fatal(err)
+		}
+		var got bin_img.Binary
+		if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
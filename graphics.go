@@ -0,0 +1,96 @@
+package tspl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+// GraphicSession tracks which graphics have been downloaded to a printer's
+// memory, so a logo repeated on many labels is sent once and then only
+// referenced. Graphics go to the printer's DRAM, which is cleared when it is
+// switched off; call Reset when that happens or when a new connection may
+// reach a different printer. It is safe for concurrent use.
+type GraphicSession struct {
+	mu         sync.Mutex
+	downloaded map[string]bool
+}
+
+func NewGraphicSession() *GraphicSession {
+	return &GraphicSession{downloaded: make(map[string]bool)}
+}
+
+// UseGraphic returns the commands drawing img with its top-left corner at
+// (x,y): the first time name is used, a DOWNLOAD storing img as a BMP file
+// under name, such as "LOGO.BMP", followed by a PUTBMP of it, and afterwards
+// only the PUTBMP. img is read with the default convention that on pixels
+// are paper. The commands can be passed as one entry of Options.Extra or
+// Parsed into Commands for a Designer.
+func (s *GraphicSession) UseGraphic(name string, img *bin_img.Binary, x, y int) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, "\"\r\n") {
+		return nil, fmt.Errorf("invalid graphic name %q", name)
+	}
+	if img.Rect.Empty() {
+		return nil, errors.New("empty graphic")
+	}
+	put := fmt.Sprintf("%s %d,%d,\"%s\"\r\n", CmdPutBMP, x, y, name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.downloaded[name] {
+		return []byte(put), nil
+	}
+	bmp := encodeBMP(img)
+	res := fmt.Appendf(nil, "%s \"%s\",%d,", CmdDownload, name, len(bmp))
+	res = append(res, bmp...)
+	res = append(res, "\r\n"...)
+	res = append(res, put...)
+	s.downloaded[name] = true
+	return res, nil
+}
+
+// Reset forgets every downloaded graphic, so each is downloaded again on its
+// next use.
+func (s *GraphicSession) Reset() {
+	s.mu.Lock()
+	s.downloaded = make(map[string]bool)
+	s.mu.Unlock()
+}
+
+// encodeBMP encodes img as a 1 bit per pixel Windows BMP, with palette index
+// 0 black and 1 white so the packed rows carry over unchanged.
+func encodeBMP(img *bin_img.Binary) []byte {
+	const headerSize = 14 + 40 + 8
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	rowBytes := (w + 7) / 8
+	stride := (w + 31) / 32 * 4
+	var raw bytes.Buffer
+	img.WriteRawTo(&raw)
+
+	res := make([]byte, headerSize+stride*h)
+	le := binary.LittleEndian
+	// BITMAPFILEHEADER
+	copy(res, "BM")
+	le.PutUint32(res[2:], uint32(len(res)))
+	le.PutUint32(res[10:], headerSize)
+	// BITMAPINFOHEADER
+	le.PutUint32(res[14:], 40)
+	le.PutUint32(res[18:], uint32(w))
+	le.PutUint32(res[22:], uint32(h))
+	le.PutUint16(res[26:], 1)
+	le.PutUint16(res[28:], 1)
+	le.PutUint32(res[34:], uint32(stride*h))
+	le.PutUint32(res[46:], 2)
+	// Palette, as blue, green, red and a reserved byte.
+	copy(res[58:], []byte{0xFF, 0xFF, 0xFF, 0})
+	// Rows are stored bottom-up.
+	rows := raw.Bytes()
+	for y := 0; y < h; y++ {
+		copy(res[headerSize+(h-1-y)*stride:], rows[y*rowBytes:(y+1)*rowBytes])
+	}
+	return res
+}
@@ -0,0 +1,41 @@
+package tspl
+
+import (
+	"image"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+// EncodeGrayscale is like Encode but approximates levels shades of gray: it
+// sends the planes of bin_img.HalftonePlanes as stacked BITMAP commands in OR
+// mode, so the printed dots are their union. Thermal heads cannot vary dot
+// darkness, so the shades come from dot density, as in a newspaper photo.
+func (t *Driver) EncodeGrayscale(w, h, dpm int, src image.Image, levels int, opt Options) ([]byte, error) {
+	if err := t.checkOptions(w, h, dpm, opt); err != nil {
+		return nil, err
+	}
+	if err := checkImageFits(w, src); err != nil {
+		return nil, err
+	}
+	planes, err := bin_img.HalftonePlanes(src, levels)
+	if err != nil {
+		return nil, err
+	}
+	// The planes are ink-off whatever InkIsOn says, so they are packed by a
+	// driver with the default convention.
+	var raw Driver
+	var body []byte
+	for _, p := range planes {
+		_, bitmap, err := raw.Image2Bytes(p)
+		if err != nil {
+			return nil, err
+		}
+		if opt.MaxBitmapRows > 0 {
+			if bitmap, err = t.SplitBitmap(bitmap, opt.MaxBitmapRows); err != nil {
+				return nil, err
+			}
+		}
+		body = append(body, bitmap...)
+	}
+	return t.assemble(w, h, dpm, body, opt, false), nil
+}
@@ -0,0 +1,222 @@
+package tspl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrQueueEmpty is returned by JobQueue.Dequeue when no job is waiting.
+var ErrQueueEmpty = errors.New("job queue is empty")
+
+const (
+	jobExt    = ".job"
+	jobTmpExt = ".tmp"
+	// jobMarkFile holds the first job ID not yet reserved, and jobIDBlock
+	// is how many IDs each write of it reserves.
+	jobMarkFile = "next-id"
+	jobIDBlock  = 1024
+)
+
+// JobQueue is a FIFO of print jobs persisted as files in a directory, giving
+// at-least-once delivery: a job stays on disk until it is acknowledged, and
+// jobs dequeued but never acknowledged are replayed when the queue is opened
+// again. It is safe for concurrent use.
+type JobQueue struct {
+	dir string
+
+	mu       sync.Mutex
+	next     uint64
+	limit    uint64 // end of the ID block persisted in jobMarkFile
+	pending  []string
+	inFlight map[string]bool
+}
+
+// NewJobQueue opens the queue stored in dir, creating dir if needed, and
+// queues every unacknowledged job found there in the order it was enqueued.
+func NewJobQueue(dir string) (*JobQueue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	q := &JobQueue{dir: dir, inFlight: make(map[string]bool)}
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case strings.HasSuffix(name, jobTmpExt):
+			// Left over from a crash before the rename; never acknowledged.
+			os.Remove(filepath.Join(dir, name))
+		case strings.HasSuffix(name, jobExt):
+			id := strings.TrimSuffix(name, jobExt)
+			n, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				continue
+			}
+			q.pending = append(q.pending, id)
+			if n >= q.next {
+				q.next = n + 1
+			}
+		}
+	}
+	sort.Strings(q.pending)
+	switch mark, err := os.ReadFile(filepath.Join(dir, jobMarkFile)); {
+	case err == nil:
+		n, err := strconv.ParseUint(strings.TrimSpace(string(mark)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid job ID mark %q", mark)
+		}
+		if n > q.next {
+			q.next = n
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+	// The rest of the block of the last run may have been used; start a new
+	// one with the first ID.
+	q.limit = q.next
+	return q, nil
+}
+
+// Enqueue writes doc to disk and queues it. The file is written under a
+// temporary name and renamed into place, so a crash never leaves a partial
+// job behind, and it is on disk before the ID is returned.
+func (q *JobQueue) Enqueue(doc []byte) (jobID string, err error) {
+	id, err := q.newID()
+	if err != nil {
+		return "", err
+	}
+	if err := writeDurable(q.dir, id+jobExt, doc); err != nil {
+		return "", err
+	}
+
+	q.mu.Lock()
+	q.insertPending(id)
+	q.mu.Unlock()
+	return id, nil
+}
+
+// insertPending queues id in ID order. A concurrent Enqueue that reserved a
+// later ID may have finished writing first, so id does not always go last.
+// q.mu must be held.
+func (q *JobQueue) insertPending(id string) {
+	i := sort.SearchStrings(q.pending, id)
+	q.pending = append(q.pending, "")
+	copy(q.pending[i+1:], q.pending[i:])
+	q.pending[i] = id
+}
+
+// newID reserves the next job ID. IDs are handed out from blocks of
+// jobIDBlock whose end is persisted in the mark file before the first ID of
+// the block is used, so an ID is never reused, even after every job was
+// acknowledged and the queue reopened.
+func (q *JobQueue) newID() (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.next >= q.limit {
+		limit := q.next + jobIDBlock
+		if err := writeDurable(q.dir, jobMarkFile, []byte(strconv.FormatUint(limit, 10))); err != nil {
+			return "", err
+		}
+		q.limit = limit
+	}
+	id := fmt.Sprintf("%020d", q.next)
+	q.next++
+	return id, nil
+}
+
+// writeDurable writes data to dir/name through a temporary file renamed into
+// place, syncing both the file and, as the rename only survives a power loss
+// once it is, the directory.
+func writeDurable(dir, name string, data []byte) (err error) {
+	f, err := os.CreateTemp(dir, name+"-*"+jobTmpExt)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Dequeue returns the oldest waiting job and its ID. The job stays on disk
+// until Ack is called with the ID.
+func (q *JobQueue) Dequeue() ([]byte, string, error) {
+	q.mu.Lock()
+	if len(q.pending) == 0 {
+		q.mu.Unlock()
+		return nil, "", ErrQueueEmpty
+	}
+	id := q.pending[0]
+	q.pending = q.pending[1:]
+	q.inFlight[id] = true
+	q.mu.Unlock()
+
+	doc, err := os.ReadFile(q.path(id))
+	if err != nil {
+		q.mu.Lock()
+		delete(q.inFlight, id)
+		q.insertPending(id)
+		q.mu.Unlock()
+		return nil, "", err
+	}
+	return doc, id, nil
+}
+
+// Ack removes a dequeued job for good.
+func (q *JobQueue) Ack(jobID string) error {
+	q.mu.Lock()
+	if !q.inFlight[jobID] {
+		q.mu.Unlock()
+		return fmt.Errorf("job %q is not in flight", jobID)
+	}
+	delete(q.inFlight, jobID)
+	q.mu.Unlock()
+	return os.Remove(q.path(jobID))
+}
+
+func (q *JobQueue) path(id string) string {
+	return filepath.Join(q.dir, id+jobExt)
+}
+
+// syncDir flushes the entries of dir to disk. Windows cannot open a
+// directory for syncing, so it is skipped there.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
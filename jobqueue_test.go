@@ -0,0 +1,141 @@
+package tspl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestJobQueueNeverReusesIDs(t *testing.T) {
+	dir := t.TempDir()
+	seen := make(map[string]bool)
+	for run := 0; run < 3; run++ {
+		q, err := NewJobQueue(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			id, err := q.Enqueue(testDoc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if seen[id] {
+				t.Fatalf("run %d: job ID %s handed out again", run, id)
+			}
+			seen[id] = true
+			if _, got, err := q.Dequeue(); err != nil || got != id {
+				t.Fatalf("Dequeue = %q, %v, want %q", got, err, id)
+			}
+			if err := q.Ack(id); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestJobQueueOrderUnderConcurrentEnqueue(t *testing.T) {
+	q, err := NewJobQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := q.Enqueue(testDoc); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	prev := ""
+	for i := 0; i < 32; i++ {
+		_, id, err := q.Dequeue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id <= prev {
+			t.Fatalf("Dequeue returned %s after %s", id, prev)
+		}
+		prev = id
+	}
+}
+
+func TestJobQueueReplaysUnackedJobs(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewJobQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := q.Enqueue([]byte{byte('a' + i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	// a is acknowledged, b dequeued but not and c never dequeued.
+	for i := 0; i < 2; i++ {
+		if _, _, err := q.Dequeue(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.Ack(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	// A crash between writing a job and renaming it leaves a .tmp file.
+	tmp := filepath.Join(dir, "00000000000000000099.job-1"+jobTmpExt)
+	if err := os.WriteFile(tmp, []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err = NewJobQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tmp); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("leftover %s was not removed: %v", tmp, err)
+	}
+	for i, want := range []string{"b", "c"} {
+		doc, id, err := q.Dequeue()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(doc) != want || id != ids[i+1] {
+			t.Errorf("replayed job %s %q, want %s %q", id, doc, ids[i+1], want)
+		}
+	}
+	if _, _, err := q.Dequeue(); err != ErrQueueEmpty {
+		t.Errorf("Dequeue of an empty queue = %v, want ErrQueueEmpty", err)
+	}
+}
+
+func TestJobQueueAckUnknownID(t *testing.T) {
+	q, err := NewJobQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := q.Enqueue(testDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Neither an ID never handed out nor one not yet dequeued is in flight.
+	for _, bad := range []string{"nope", id} {
+		if err := q.Ack(bad); err == nil {
+			t.Errorf("Ack(%q) succeeded", bad)
+		}
+	}
+	if _, _, err := q.Dequeue(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Ack(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Ack(id); err == nil {
+		t.Error("acknowledging a job twice succeeded")
+	}
+}
@@ -0,0 +1,95 @@
+package tspl
+
+import (
+	"bufio"
+	"io"
+)
+
+// maxJobSize bounds the size of a single job read by a JobScanner.
+const maxJobSize = 64 << 20
+
+// JobScanner splits a stream of concatenated TSPL jobs, such as a spool
+// file, into one job at a time without reading the whole stream. A job ends
+// with its PRINT command and any DELAY and PRINT commands straight after it,
+// as Options.Delay emits, so a job is only complete once the command after it
+// or the end of the stream is read. BITMAP payloads are skipped by the length
+// in their header, so binary data never ends a job early. Trailing commands
+// without a PRINT make up a last job of their own.
+//
+// Its Scan, Job and Err methods work like those of bufio.Scanner. A job may
+// be at most 64 MiB.
+type JobScanner struct {
+	sc *bufio.Scanner
+	// off is how far into the pending data the current job has been split
+	// into commands, so that a job arriving in pieces is not split again.
+	// end, if not 0, is where the job ends unless more PRINTs follow.
+	off, end int
+}
+
+func NewJobScanner(r io.Reader) *JobScanner {
+	s := &JobScanner{sc: bufio.NewScanner(r)}
+	s.sc.Buffer(nil, maxJobSize)
+	s.sc.Split(s.split)
+	return s
+}
+
+// Scan advances to the next job, returning false at the end of the stream
+// or on an error.
+func (s *JobScanner) Scan() bool { return s.sc.Scan() }
+
+// Job returns the raw bytes of the current job, from its first command up to
+// and including the line ending of its PRINT. The slice is only valid until
+// the next call to Scan.
+func (s *JobScanner) Job() []byte { return s.sc.Bytes() }
+
+// Err returns the first error met, such as a truncated BITMAP payload.
+func (s *JobScanner) Err() error { return s.sc.Err() }
+
+func (s *JobScanner) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) && isSpace(data[start]) {
+		start++
+	}
+	if s.off < start {
+		s.off = start
+	}
+	for s.off < len(data) {
+		n, cmd, err := splitCommand(data[s.off:], atEOF)
+		if err != nil {
+			return 0, nil, err
+		}
+		if cmd == nil {
+			if !atEOF {
+				break
+			}
+			// Only spaces are left.
+			s.off += n
+			continue
+		}
+		name, _ := splitWord(cmd)
+		if s.end > 0 && !CmdPrint.is(name) && !CmdDelay.is(name) {
+			end := s.end
+			s.off, s.end = 0, 0
+			return end, data[start:end], nil
+		}
+		s.off += n
+		if s.end > 0 || CmdPrint.is(name) {
+			s.end = s.off
+		}
+	}
+	switch {
+	case atEOF && s.end > 0:
+		// Only spaces follow the last PRINT.
+		end := s.end
+		s.off, s.end = 0, 0
+		return len(data), data[start:end], nil
+	case atEOF && s.off > start:
+		s.off = 0
+		return len(data), data[start:], nil
+	case s.off == start:
+		// Nothing but spaces so far; drop them.
+		s.off = 0
+		return start, nil, nil
+	}
+	return 0, nil, nil
+}
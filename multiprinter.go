@@ -0,0 +1,88 @@
+package tspl
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// multiPrinterConns is how many connections each printer in a MultiPrinter
+// keeps open.
+const multiPrinterConns = 2
+
+// MultiPrinter spreads jobs over several printers, sending each job to the
+// printer with the fewest jobs in flight. It is safe for concurrent use.
+type MultiPrinter struct {
+	printers []*pooledPrinter
+}
+
+type pooledPrinter struct {
+	addr    string
+	session *SessionManager
+	pending atomic.Int64
+	printed atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// PrinterStats is a snapshot of one printer's counters.
+type PrinterStats struct {
+	Address string
+	Pending int64
+	Printed uint64
+	Failed  uint64
+}
+
+// NewMultiPrinter creates a MultiPrinter over printers given as "host:port"
+// addresses.
+func NewMultiPrinter(addresses []string) *MultiPrinter {
+	m := &MultiPrinter{printers: make([]*pooledPrinter, len(addresses))}
+	for i, addr := range addresses {
+		m.printers[i] = &pooledPrinter{
+			addr:    addr,
+			session: newSessionManager(addr, multiPrinterConns),
+		}
+	}
+	return m
+}
+
+// Print sends doc to the least-loaded printer.
+func (m *MultiPrinter) Print(doc []byte) error {
+	if len(m.printers) == 0 {
+		return errors.New("no printers")
+	}
+	p := m.printers[0]
+	for _, q := range m.printers[1:] {
+		if q.pending.Load() < p.pending.Load() {
+			p = q
+		}
+	}
+	p.pending.Add(1)
+	defer p.pending.Add(-1)
+	if err := p.session.Print(doc); err != nil {
+		p.failed.Add(1)
+		return err
+	}
+	p.printed.Add(1)
+	return nil
+}
+
+// Stats returns the counters of every printer, in the order given to
+// NewMultiPrinter.
+func (m *MultiPrinter) Stats() []PrinterStats {
+	stats := make([]PrinterStats, len(m.printers))
+	for i, p := range m.printers {
+		stats[i] = PrinterStats{
+			Address: p.addr,
+			Pending: p.pending.Load(),
+			Printed: p.printed.Load(),
+			Failed:  p.failed.Load(),
+		}
+	}
+	return stats
+}
+
+// Close closes the connections to every printer.
+func (m *MultiPrinter) Close() {
+	for _, p := range m.printers {
+		p.session.Close()
+	}
+}
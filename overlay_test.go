@@ -0,0 +1,78 @@
+package tspl
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"testing"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+// overlayRef is OverlayBinaryInvert done one pixel at a time.
+func overlayRef(d *Driver, base []byte, overlay *bin_img.Binary, xOff, yOff int, invert bool) []byte {
+	h, err := d.ParseBitmapHeader(base)
+	if err != nil {
+		panic(err)
+	}
+	res := append([]byte(nil), base...)
+	r := overlay.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var bit uint8
+			if overlay.IsWhite(x, y) != (d.InkIsOn != invert) {
+				bit = 1
+			}
+			setBitmapBit(res, h.HeaderEnd, h.RowBytes, x-r.Min.X+xOff, y-r.Min.Y+yOff, bit)
+		}
+	}
+	return res
+}
+
+func TestOverlayFastPathMatchesBitPath(t *testing.T) {
+	const rowBytes, height = 6, 20
+	base := []byte(DefaultDriver.BitmapHeader(rowBytes, height, 0, 0, BitmapModeOR))
+	for i := 0; i < rowBytes*height; i++ {
+		base = append(base, byte(i*67+5))
+	}
+	parent, err := bin_img.NewBinary(40, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range parent.Pix {
+		parent.Pix[i] = byte(i*131 + 17)
+	}
+	for _, ov := range []image.Rectangle{
+		image.Rect(0, 0, 16, 8),  // aligned at the origin
+		image.Rect(0, 0, 13, 8),  // width not a multiple of 8
+		image.Rect(8, 3, 32, 12), // aligned view away from the origin
+		image.Rect(16, 5, 21, 9), // narrower than a byte
+		image.Rect(5, 2, 22, 10), // unaligned view
+	} {
+		overlay := parent.SubImage(ov).(*bin_img.Binary)
+		for _, xOff := range []int{0, 8, 3, 13} {
+			if xOff+ov.Dx() > rowBytes*8 {
+				continue
+			}
+			for _, d := range []*Driver{{}, {InkIsOn: true}} {
+				for _, invert := range []bool{false, true} {
+					name := fmt.Sprintf("overlay %v at x=%d, InkIsOn=%v, invert=%v", ov, xOff, d.InkIsOn, invert)
+					got, err := d.OverlayBinaryInvert(nil, base, overlay, xOff, 4, invert)
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					if want := overlayRef(d, base, overlay, xOff, 4, invert); !bytes.Equal(got, want) {
+						t.Errorf("%s: got %x, want %x", name, got, want)
+					}
+					if invert {
+						continue
+					}
+					plain, err := d.OverlayBinary(nil, base, overlay, xOff, 4)
+					if err != nil || !bytes.Equal(plain, got) {
+						t.Errorf("%s: OverlayBinary differs from OverlayBinaryInvert: %v", name, err)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package tspl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// ParsePrint finds the first PRINT command in program and returns its
+// arguments: PRINT m[,n] prints m label sets of n copies each. A missing n
+// defaults to 1.
+func ParsePrint(program []byte) (sets, copies int, err error) {
+	found := false
+	scanErr := scanCommands(program, func(cmd []byte) bool {
+		name, args := splitWord(cmd)
+		if !CmdPrint.is(name) {
+			return true
+		}
+		found = true
+		sets, copies, err = parsePrintArgs(args)
+		return false
+	})
+	if !found {
+		if scanErr != nil {
+			return 0, 0, scanErr
+		}
+		return 0, 0, errors.New("no PRINT command")
+	}
+	return sets, copies, err
+}
+
+func parsePrintArgs(args []byte) (sets, copies int, err error) {
+	fields := bytes.Split(args, []byte(","))
+	if len(fields) > 2 {
+		return 0, 0, fmt.Errorf("invalid PRINT arguments %q", args)
+	}
+	sets, copies = 1, 1
+	for i, f := range fields {
+		f = bytes.TrimSpace(f)
+		if len(f) == 0 {
+			if i == 0 {
+				return 0, 0, fmt.Errorf("invalid PRINT arguments %q", args)
+			}
+			continue
+		}
+		n, err := strconv.Atoi(string(f))
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid PRINT arguments %q", args)
+		}
+		if i == 0 {
+			sets = n
+		} else {
+			copies = n
+		}
+	}
+	return sets, copies, nil
+}
+
+// Command is one command of a TSPL program.
+type Command struct {
+	// Name is the command keyword as written, e.g. "SIZE".
+	Name string
+	// Args are the comma-separated arguments with the spaces around each
+	// trimmed. Quoted strings keep their quotes.
+	Args []string
+	// Data is the binary payload of a BITMAP command or of a DOWNLOAD of a
+	// file, and nil for all others. It aliases the parsed program.
+	Data []byte
+}
+
+// Parse splits program into its commands. It accepts arbitrary input: a
+// malformed BITMAP header is kept as an ordinary line, and a BITMAP whose
+// payload is cut short ends parsing with an error alongside the commands
+// parsed so far.
+func Parse(program []byte) ([]Command, error) {
+	var cmds []Command
+	err := scanCommands(program, func(cmd []byte) bool {
+		if bytes.HasPrefix(cmd, []byte(CmdBitmap)) {
+			if h, err := parseBitmapHeader(cmd); err == nil && h.HeaderEnd <= len(cmd) {
+				cmds = append(cmds, Command{
+					Name: string(CmdBitmap),
+					Args: parseArgs(cmd[len(CmdBitmap) : h.HeaderEnd-1]),
+					Data: cmd[h.HeaderEnd:],
+				})
+				return true
+			}
+		}
+		if bytes.HasPrefix(cmd, []byte(CmdDownload)) {
+			if end, _, err := downloadHeader(cmd); err == nil {
+				cmds = append(cmds, Command{
+					Name: string(CmdDownload),
+					Args: parseArgs(cmd[len(CmdDownload) : end-1]),
+					Data: cmd[end:],
+				})
+				return true
+			}
+		}
+		name, args := splitWord(cmd)
+		cmds = append(cmds, Command{Name: string(name), Args: parseArgs(args)})
+		return true
+	})
+	return cmds, err
+}
+
+func parseArgs(args []byte) []string {
+	if len(bytes.TrimSpace(args)) == 0 {
+		return nil
+	}
+	fields := splitArgs(args)
+	res := make([]string, len(fields))
+	for i, f := range fields {
+		res[i] = string(f)
+	}
+	return res
+}
+
+// appendTo appends c to dst as it would appear in a program: a BITMAP or a
+// DOWNLOAD with data is followed directly by its payload, any other command
+// by \r\n.
+func (c Command) appendTo(dst []byte) []byte {
+	dst = append(dst, c.Name...)
+	for i, a := range c.Args {
+		if i == 0 {
+			dst = append(dst, ' ')
+		} else {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, a...)
+	}
+	if CmdBitmap.is([]byte(c.Name)) || CmdDownload.is([]byte(c.Name)) && c.Data != nil {
+		dst = append(dst, ',')
+		return append(dst, c.Data...)
+	}
+	return append(dst, "\r\n"...)
+}
+
+// ParseOptions reconstructs the Options that produce the header of program's
+// first label, so a decoded label can be tweaked and encoded again. It reads
+// SET PEEL, CUTTER and HEAD_CLOSE, the partial cutter spelling (for
+// Dialect), OFFSET, SHIFT, SPEED, DENSITY, the unit of SIZE, CLS and PRINT,
+// including the DELAYs between PRINTs that Delay emits, and collects the
+// commands between the last BITMAP and PRINT as Extra. Settings and commands
+// Options has no field for are ignored.
+func ParseOptions(program []byte) (Options, error) {
+	cmds, err := Parse(program)
+	if err != nil {
+		return Options{}, err
+	}
+	opt := Options{NoClear: true}
+	afterBitmap := false
+	for i, cmd := range cmds {
+		name := []byte(cmd.Name)
+		switch {
+		case CmdPrint.is(name):
+			if opt.Sets, opt.Copies, err = parsePrintArgs([]byte(strings.Join(cmd.Args, ","))); err != nil {
+				return Options{}, err
+			}
+			if opt.Sets == 1 {
+				opt.Sets, opt.Delay = parseDelayedPrints(cmds[i+1:], opt.Copies)
+			}
+			return opt, nil
+		case CmdBitmap.is(name):
+			afterBitmap, opt.Extra = true, nil
+		case afterBitmap:
+			opt.Extra = append(opt.Extra, string(bytes.TrimSuffix(cmd.appendTo(nil), []byte("\r\n"))))
+		case CmdSet.is(name) && len(cmd.Args) == 1:
+			key, val := splitWord([]byte(cmd.Args[0]))
+			switch {
+			case SetPeel.is(key):
+				if opt.Peel, err = parseOnOff(val); err != nil {
+					return Options{}, err
+				}
+			case SetCutter.is(key):
+				// Besides ON, CUTTER takes a label count or BATCH.
+				opt.Cutter = !bytes.EqualFold(val, []byte("OFF"))
+			case SetHeadClose.is(key):
+				opt.HeadClose = !bytes.Equal(val, []byte("0"))
+			case SetPartialCutter.is(key):
+				opt.Dialect = DialectTSPL2
+			case SetParticalCutter.is(key):
+				opt.Dialect = DialectTSPL
+			}
+		case CmdOffset.is(name) && len(cmd.Args) == 1:
+			v, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(cmd.Args[0], "mm")), 64)
+			if err != nil {
+				return Options{}, fmt.Errorf("invalid OFFSET %q", cmd.Args[0])
+			}
+			opt.Offset = &v
+		case CmdShift.is(name) && len(cmd.Args) == 1:
+			v, err := strconv.Atoi(cmd.Args[0])
+			if err != nil {
+				return Options{}, fmt.Errorf("invalid SHIFT %q", cmd.Args[0])
+			}
+			opt.Shift = &v
+		case CmdSpeed.is(name) && len(cmd.Args) == 1:
+			v, err := strconv.ParseFloat(cmd.Args[0], 64)
+			if err != nil {
+				return Options{}, fmt.Errorf("invalid SPEED %q", cmd.Args[0])
+			}
+			opt.Speed = &v
+		case CmdDensity.is(name) && len(cmd.Args) == 1:
+			v, err := strconv.Atoi(cmd.Args[0])
+			if err != nil {
+				return Options{}, fmt.Errorf("invalid DENSITY %q", cmd.Args[0])
+			}
+			opt.Density = &v
+		case CmdSize.is(name) && len(cmd.Args) > 0:
+			switch {
+			case strings.HasSuffix(cmd.Args[0], "mm"):
+				opt.Unit = SizeUnitMM
+			case strings.HasSuffix(cmd.Args[0], "dot"):
+				opt.Unit = SizeUnitDots
+			default:
+				opt.Unit = SizeUnitInch
+			}
+		case CmdCls.is(name):
+			opt.NoClear, opt.ClearRegion = false, nil
+			if len(cmd.Args) == 4 {
+				var v [4]int
+				for i, a := range cmd.Args {
+					if v[i], err = strconv.Atoi(a); err != nil {
+						return Options{}, fmt.Errorf("invalid CLS arguments %q", cmd.Args)
+					}
+				}
+				r := image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3])
+				opt.ClearRegion = &r
+			}
+		}
+	}
+	return opt, nil
+}
+
+// parseDelayedPrints counts the label sets printed by a PRINT 1,copies and
+// the DELAY and PRINT 1,copies pairs in cmds following it, and returns them
+// with the delay.
+func parseDelayedPrints(cmds []Command, copies int) (sets, delay int) {
+	sets = 1
+	for len(cmds) >= 2 && CmdDelay.is([]byte(cmds[0].Name)) && len(cmds[0].Args) == 1 &&
+		CmdPrint.is([]byte(cmds[1].Name)) {
+		d, err := strconv.Atoi(cmds[0].Args[0])
+		if err != nil || d <= 0 || delay != 0 && d != delay {
+			break
+		}
+		s, c, err := parsePrintArgs([]byte(strings.Join(cmds[1].Args, ",")))
+		if err != nil || s != 1 || c != copies {
+			break
+		}
+		sets, delay = sets+1, d
+		cmds = cmds[2:]
+	}
+	return sets, delay
+}
+
+func parseOnOff(s []byte) (bool, error) {
+	switch {
+	case bytes.EqualFold(s, []byte("ON")):
+		return true, nil
+	case bytes.EqualFold(s, []byte("OFF")):
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid ON/OFF value %q", s)
+}
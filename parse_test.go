@@ -0,0 +1,43 @@
+package tspl
+
+import (
+	"image"
+	"runtime"
+	"testing"
+)
+
+// maxParseAlloc bounds the bytes Parse may allocate for an n-byte program.
+func maxParseAlloc(n int) uint64 { return 64*uint64(n) + 64<<10 }
+
+func FuzzParse(f *testing.F) {
+	img := image.NewGray(image.Rect(0, 0, 16, 4))
+	doc, err := DefaultDriver.Encode(40, 30, 8, img, Options{Extra: []string{`TEXT 10,10,"3",0,1,1,"a, b"`}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(doc)
+	f.Add(doc[:len(doc)/2])
+	f.Add([]byte("BITMAP 0,0,2,9999,0,\x00\x01"))
+	f.Add([]byte("BITMAP 0,0,-2,-1,0,"))
+	f.Add([]byte("DOWNLOAD \"A.BMP\",99999999,BM"))
+	f.Add([]byte("DOWNLOAD \"A.BMP\",-1,"))
+	f.Add([]byte("SIZE 4 mm,3 mm\r\nPRINT 1,2\r\nDELAY 10\r\nPRINT 1,2\r\n"))
+	f.Add([]byte("TEXT 1,1,\"unterminated"))
+
+	f.Fuzz(func(t *testing.T, program []byte) {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		cmds, err := Parse(program)
+		runtime.ReadMemStats(&after)
+		if n := after.TotalAlloc - before.TotalAlloc; n > maxParseAlloc(len(program)) {
+			t.Fatalf("Parse allocated %d bytes for a %d-byte program", n, len(program))
+		}
+		if err == nil {
+			for _, c := range cmds {
+				c.appendTo(nil)
+			}
+		}
+		ParseOptions(program)
+		ParsePrint(program)
+	})
+}
@@ -0,0 +1,224 @@
+package tspl
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// prettyBitmapBytes is how many payload bytes PrettyPrintSummary shows per
+// BITMAP or DOWNLOAD.
+const prettyBitmapBytes = 16
+
+// PrettyPrint reformats a TSPL document for reading: one command per line
+// ending in \r\n, command names padded to a common column and the arguments
+// of commands with the same name aligned in columns, each padded after its
+// comma.
+//
+// Only whitespace between arguments changes, so the output prints the same
+// labels as tsplDoc. BITMAP and DOWNLOAD keep their header and binary payload
+// byte for byte; see PrettyPrintSummary for a text-only log line instead.
+func PrettyPrint(tsplDoc []byte) string {
+	return prettyPrint(tsplDoc, false)
+}
+
+// PrettyPrintSummary is like PrettyPrint but replaces BITMAP and DOWNLOAD
+// payloads with a short hex summary, which makes the output a log aid rather
+// than a printable program.
+func PrettyPrintSummary(tsplDoc []byte) string {
+	return prettyPrint(tsplDoc, true)
+}
+
+func prettyPrint(tsplDoc []byte, summary bool) string {
+	type line struct {
+		name string
+		args []string
+		// raw, when set, follows the name verbatim instead of args.
+		raw string
+	}
+	var lines []line
+	width := 0
+	cols := map[string][]int{}
+	err := scanCommands(tsplDoc, func(cmd []byte) bool {
+		var l line
+		end := 0
+		if bytes.HasPrefix(cmd, []byte(CmdBitmap)) {
+			if h, err := parseBitmapHeader(cmd); err == nil {
+				l.name, end = string(CmdBitmap), h.HeaderEnd
+			}
+		}
+		if bytes.HasPrefix(cmd, []byte(CmdDownload)) {
+			if n, _, err := downloadHeader(cmd); err == nil {
+				l.name, end = string(CmdDownload), n
+			}
+		}
+		if l.name != "" {
+			l.raw = string(bytes.TrimLeft(cmd[len(l.name):end], " \t"))
+			if summary {
+				l.raw += hexSummary(cmd[end:], prettyBitmapBytes)
+			} else {
+				l.raw += string(cmd[end:])
+			}
+		} else {
+			name, args := splitWord(cmd)
+			l.name, l.args = string(name), prettyArgs(args)
+			w := cols[l.name]
+			for i, a := range l.args {
+				if i == len(w) {
+					w = append(w, 0)
+				}
+				if len(a) > w[i] {
+					w[i] = len(a)
+				}
+			}
+			cols[l.name] = w
+		}
+		if len(l.name) > width {
+			width = len(l.name)
+		}
+		lines = append(lines, l)
+		return true
+	})
+
+	var b strings.Builder
+	for _, l := range lines {
+		switch {
+		case l.raw != "":
+			fmt.Fprintf(&b, "%-*s %s", width, l.name, l.raw)
+		case len(l.args) == 0:
+			b.WriteString(l.name)
+		default:
+			fmt.Fprintf(&b, "%-*s ", width, l.name)
+			for i, a := range l.args {
+				b.WriteString(a)
+				if i < len(l.args)-1 {
+					fmt.Fprintf(&b, ",%*s", cols[l.name][i]-len(a)+1, "")
+				}
+			}
+		}
+		b.WriteString("\r\n")
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "; %v\r\n", err)
+	}
+	return b.String()
+}
+
+// prettyArgs returns the comma-separated arguments of a command, trimmed.
+func prettyArgs(args []byte) []string {
+	if len(bytes.TrimSpace(args)) == 0 {
+		return nil
+	}
+	parts := splitArgs(args)
+	strs := make([]string, len(parts))
+	for i, p := range parts {
+		strs[i] = string(p)
+	}
+	return strs
+}
+
+// hexSummary renders up to n leading bytes of data as hex.
+func hexSummary(data []byte, n int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d bytes:", len(data))
+	for i, c := range data {
+		if i == n {
+			b.WriteString(" ...")
+			break
+		}
+		fmt.Fprintf(&b, " %02x", c)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// ANSI escape sequences used by ColorTSPL.
+const (
+	ansiReset    = "\x1b[0m"
+	ansiBoldCyan = "\x1b[1;36m"
+	ansiYellow   = "\x1b[33m"
+	ansiGreen    = "\x1b[32m"
+	ansiDim      = "\x1b[2m"
+)
+
+// ColorTSPL highlights a TSPL document for a terminal, one command per line
+// ending in \r\n: command names are bold cyan, numbers yellow and quoted
+// strings green, while BITMAP and DOWNLOAD payloads are replaced by a dim
+// [N bytes] placeholder. If the NO_COLOR environment variable is set and not
+// empty, the same text is returned without escape sequences.
+//
+// Like PrettyPrintSummary, it is a reading aid and not a printable program.
+func ColorTSPL(tsplDoc []byte) string {
+	c := colorizer{on: os.Getenv("NO_COLOR") == ""}
+	err := scanCommands(tsplDoc, func(cmd []byte) bool {
+		args, payload := cmd, []byte(nil)
+		if bytes.HasPrefix(cmd, []byte(CmdBitmap)) {
+			if h, err := parseBitmapHeader(cmd); err == nil {
+				args, payload = cmd[:h.HeaderEnd-1], cmd[h.HeaderEnd:]
+			}
+		}
+		if bytes.HasPrefix(cmd, []byte(CmdDownload)) {
+			if end, _, err := downloadHeader(cmd); err == nil {
+				args, payload = cmd[:end-1], cmd[end:]
+			}
+		}
+		name, args := splitWord(args)
+		c.paint(ansiBoldCyan, string(name))
+		if len(bytes.TrimSpace(args)) > 0 {
+			c.b.WriteByte(' ')
+			for i, arg := range splitArgs(args) {
+				if i > 0 {
+					c.b.WriteByte(',')
+				}
+				c.arg(arg)
+			}
+		}
+		if payload != nil {
+			c.b.WriteByte(',')
+			c.paint(ansiDim, fmt.Sprintf("[%d bytes]", len(payload)))
+		}
+		c.b.WriteString("\r\n")
+		return true
+	})
+	if err != nil {
+		fmt.Fprintf(&c.b, "; %v\r\n", err)
+	}
+	return c.b.String()
+}
+
+// colorizer builds ColorTSPL's output, with escape sequences only if on.
+type colorizer struct {
+	b  strings.Builder
+	on bool
+}
+
+func (c *colorizer) paint(code, s string) {
+	if !c.on {
+		c.b.WriteString(s)
+		return
+	}
+	c.b.WriteString(code)
+	c.b.WriteString(s)
+	c.b.WriteString(ansiReset)
+}
+
+// arg writes one command argument: a quoted string in green, or its
+// space-separated words with numbers in yellow, such as the 40 of "40 mm".
+func (c *colorizer) arg(arg []byte) {
+	if len(arg) > 0 && arg[0] == '"' {
+		c.paint(ansiGreen, string(arg))
+		return
+	}
+	for i, w := range strings.Fields(string(arg)) {
+		if i > 0 {
+			c.b.WriteByte(' ')
+		}
+		if _, err := strconv.ParseFloat(w, 64); err == nil {
+			c.paint(ansiYellow, w)
+		} else {
+			c.b.WriteString(w)
+		}
+	}
+}
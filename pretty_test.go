@@ -0,0 +1,75 @@
+package tspl
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+)
+
+var prettyDoc = []byte("SIZE 40 mm,30 mm\r\nCLS\r\n" +
+	"TEXT 10,20,\"3\",0,1,1,\"A, B\"\r\nTEXT 100,200,\"3\",0,1,1,\"C\"\r\n" +
+	"BITMAP 0,0,1,2,1,\r\n" +
+	"DOWNLOAD F,\"X.BMP\",3,a,cPRINT 1,1\r\n")
+
+// commands returns the commands of doc with their arguments trimmed.
+func commands(t *testing.T, doc []byte) []string {
+	t.Helper()
+	var cmds []string
+	err := scanCommands(doc, func(cmd []byte) bool {
+		name, args := splitWord(cmd)
+		cmds = append(cmds, string(name)+" "+string(bytes.Join(splitArgs(args), []byte(","))))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cmds
+}
+
+func TestPrettyPrint(t *testing.T) {
+	want := "SIZE     40 mm, 30 mm\r\n" +
+		"CLS\r\n" +
+		"TEXT     10,  20,  \"3\", 0, 1, 1, \"A, B\"\r\n" +
+		"TEXT     100, 200, \"3\", 0, 1, 1, \"C\"\r\n" +
+		"BITMAP   0,0,1,2,1,\r\n\r\n" +
+		"DOWNLOAD F,\"X.BMP\",3,a,c\r\n" +
+		"PRINT    1, 1\r\n"
+	got := PrettyPrint(prettyDoc)
+	if got != want {
+		t.Fatalf("PrettyPrint =\n%q\nwant\n%q", got, want)
+	}
+	before, after := commands(t, prettyDoc), commands(t, []byte(got))
+	if strings.Join(before, "\n") != strings.Join(after, "\n") {
+		t.Errorf("PrettyPrint changed the commands:\n%q\nto\n%q", before, after)
+	}
+}
+
+func TestPrettyPrintEncoded(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		// Arbitrary levels, so the payload holds arbitrary bytes.
+		img.Pix[i] = uint8(i * 97)
+	}
+	doc, err := DefaultDriver.Encode(16, 16, 8, img, Options{Extra: []string{`TEXT 8,8,"1",0,1,1,"x"`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pretty := PrettyPrint(doc)
+	before, after := commands(t, doc), commands(t, []byte(pretty))
+	if strings.Join(before, "\n") != strings.Join(after, "\n") {
+		t.Errorf("PrettyPrint changed the commands:\n%q\nto\n%q", before, after)
+	}
+}
+
+func TestPrettyPrintSummary(t *testing.T) {
+	got := PrettyPrintSummary(prettyDoc)
+	for _, want := range []string{
+		"BITMAP   0,0,1,2,1,<2 bytes: 0d 0a>\r\n",
+		"DOWNLOAD F,\"X.BMP\",3,<3 bytes: 61 2c 63>\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("PrettyPrintSummary =\n%q\nwant it to contain %q", got, want)
+		}
+	}
+}
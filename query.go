@@ -0,0 +1,33 @@
+package tspl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// VersionQuery returns commands that make the printer send back its model
+// name and firmware version, the _MODEL$ and _VERSION$ system variables, one
+// per line. Parse the reply with ParseVersion.
+func VersionQuery() []byte {
+	return []byte(fmt.Sprintf("%s \"\",_MODEL$\r\n%s \"\",_VERSION$\r\n", CmdOut, CmdOut))
+}
+
+// ParseVersion parses the reply to VersionQuery. Models differ in whether
+// they end lines with CR, LF or both and may pad the values with spaces, so
+// blank lines and surrounding spaces are ignored.
+func ParseVersion(reply []byte) (model, firmware string, err error) {
+	var fields []string
+	for _, line := range bytes.FieldsFunc(reply, func(r rune) bool { return r == '\r' || r == '\n' }) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			fields = append(fields, string(line))
+		}
+	}
+	switch len(fields) {
+	case 2:
+		return fields[0], fields[1], nil
+	case 0, 1:
+		return "", "", errors.New("incomplete version reply")
+	}
+	return "", "", fmt.Errorf("unexpected version reply %q", reply)
+}
@@ -0,0 +1,34 @@
+package tspl
+
+import "testing"
+
+func TestVersionQuery(t *testing.T) {
+	want := "OUT \"\",_MODEL$\r\nOUT \"\",_VERSION$\r\n"
+	if got := string(VersionQuery()); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for _, tt := range []struct {
+		reply           string
+		model, firmware string
+		ok              bool
+	}{
+		{"TTP-244 PRO\r\nV6.89 EZ\r\n", "TTP-244 PRO", "V6.89 EZ", true},
+		{"TTP-244 PRO\nV6.89 EZ\n", "TTP-244 PRO", "V6.89 EZ", true},
+		{"TTP-244 PRO\rV6.89 EZ\r", "TTP-244 PRO", "V6.89 EZ", true},
+		{"TTP-244 PRO\r\nV6.89 EZ", "TTP-244 PRO", "V6.89 EZ", true},
+		{"  TE200   \r\n\tV1.2  \r\n", "TE200", "V1.2", true},
+		{"\r\n\r\nDA220\r\n\r\nV3.0\r\n\r\n", "DA220", "V3.0", true},
+		{"", "", "", false},
+		{"\r\n \r\n", "", "", false},
+		{"TE200\r\n", "", "", false},
+		{"TE200\r\nV1.2\r\nextra\r\n", "", "", false},
+	} {
+		model, firmware, err := ParseVersion([]byte(tt.reply))
+		if (err == nil) != tt.ok || model != tt.model || firmware != tt.firmware {
+			t.Errorf("ParseVersion(%q) = %q, %q, %v", tt.reply, model, firmware, err)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package tspl
+
+import (
+	"context"
+	"crypto/sha256"
+	"image"
+)
+
+// EncodeWithReceipt is like Encode but also returns the SHA-256 fingerprint
+// of the BITMAP command, so an audit trail can store the fingerprint alone
+// and later check a label against it. The header and PRINT commands are
+// left out of the fingerprint, as they may vary from job to job, and it is
+// taken before any MaxBitmapRows split.
+func (t *Driver) EncodeWithReceipt(w, h, dpm int, img image.Image, opt Options) (doc []byte, fingerprint [32]byte, err error) {
+	if err := checkImageFits(w, img); err != nil {
+		return nil, fingerprint, err
+	}
+	_, bitmap, err := t.image2Bytes(context.Background(), img)
+	if err != nil {
+		return nil, fingerprint, err
+	}
+	doc, err = t.EncodeWithBitmap(w, h, dpm, bitmap, opt)
+	if err != nil {
+		return nil, fingerprint, err
+	}
+	return doc, sha256.Sum256(bitmap), nil
+}
@@ -0,0 +1,218 @@
+package tspl
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// maxBitmapHeaderLen bounds how far splitCommand looks for the end of a
+// BITMAP header before giving up on it.
+const maxBitmapHeaderLen = 64
+
+var errTruncatedBitmap = errors.New("truncated BITMAP payload")
+
+// splitCommand is a bufio.SplitFunc that yields one TSPL command per token.
+//
+// A command ends at a line feed outside double quotes and the trailing
+// carriage return is dropped. BITMAP commands carry a binary payload whose
+// length is taken from the header, so the payload is returned as part of the
+// token and never searched for line endings. The same goes for DOWNLOAD
+// commands storing a file of a given size. Blank lines yield no token.
+func splitCommand(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) && isSpace(data[start]) {
+		start++
+	}
+	if start == len(data) {
+		if atEOF {
+			return len(data), nil, nil
+		}
+		return start, nil, nil
+	}
+	rest := data[start:]
+
+	if bytes.HasPrefix(rest, []byte(CmdBitmap)) {
+		n, err := bitmapCommandLen(rest)
+		switch {
+		case err == nil && n <= len(rest):
+			return start + n, rest[:n], nil
+		case err == nil || err == errShortHeader:
+			if !atEOF {
+				return start, nil, nil
+			}
+			return 0, nil, errTruncatedBitmap
+		}
+		// Not a well-formed BITMAP header; treat it as an ordinary line.
+	}
+	if bytes.HasPrefix(rest, []byte(CmdDownload)) {
+		end, size, err := downloadHeader(rest)
+		switch {
+		case err == nil && end+size <= len(rest):
+			return start + end + size, rest[:end+size], nil
+		case err == nil || err == errShortHeader:
+			if !atEOF {
+				return start, nil, nil
+			}
+			return 0, nil, errors.New("truncated DOWNLOAD payload")
+		}
+		// A DOWNLOAD of a program, ended by EOP, or not well formed.
+	}
+
+	quoted := false
+	for i, b := range rest {
+		switch b {
+		case '"':
+			quoted = !quoted
+		case '\n':
+			if !quoted {
+				return start + i + 1, bytes.TrimRight(rest[:i], "\r"), nil
+			}
+		}
+	}
+	if atEOF {
+		return len(data), bytes.TrimRight(rest, "\r"), nil
+	}
+	return start, nil, nil
+}
+
+var errShortHeader = errors.New("short BITMAP header")
+
+// bitmapCommandLen returns the length of the BITMAP command at the start of
+// data, header and payload included. errShortHeader means data ends before
+// the header does.
+func bitmapCommandLen(data []byte) (int, error) {
+	head := data
+	if len(head) > maxBitmapHeaderLen {
+		head = head[:maxBitmapHeaderLen]
+	}
+	if i := bytes.IndexByte(head, '\n'); i >= 0 && bytes.Count(head[:i], []byte(",")) < 5 {
+		return 0, errors.New("invalid BITMAP format")
+	}
+	if bytes.Count(head, []byte(",")) < 5 {
+		if len(data) < maxBitmapHeaderLen {
+			return 0, errShortHeader
+		}
+		return 0, errors.New("invalid BITMAP format")
+	}
+	h, err := parseBitmapHeader(head)
+	if err != nil {
+		return 0, err
+	}
+	if h.RowBytes <= 0 || h.Height <= 0 {
+		return 0, errors.New("invalid BITMAP size")
+	}
+	n := h.RowBytes * h.Height
+	if n/h.Height != h.RowBytes || h.HeaderEnd+n < n {
+		return 0, errors.New("BITMAP size overflows")
+	}
+	return h.HeaderEnd + n, nil
+}
+
+// maxDownloadHeaderLen bounds how far downloadHeader looks for the end of a
+// DOWNLOAD header.
+const maxDownloadHeaderLen = 128
+
+// downloadHeader parses the header of a DOWNLOAD command storing a file,
+// DOWNLOAD [n,]"NAME",SIZE, and returns its length and the size of the file
+// data that follows. errShortHeader means data ends before the header does.
+func downloadHeader(data []byte) (end, size int, err error) {
+	i := len(CmdDownload)
+	if len(data) > maxDownloadHeaderLen {
+		data = data[:maxDownloadHeaderLen]
+	}
+	short := func() (int, int, error) {
+		if len(data) < maxDownloadHeaderLen {
+			return 0, 0, errShortHeader
+		}
+		return 0, 0, errors.New("invalid DOWNLOAD format")
+	}
+	q := bytes.IndexByte(data[i:], '"')
+	if q < 0 {
+		return short()
+	}
+	// Only a memory letter, such as F for flash, may precede the name.
+	if prefix := bytes.TrimSpace(data[i : i+q]); len(prefix) > 0 &&
+		!(len(prefix) == 2 && prefix[1] == ',' && prefix[0] >= 'A' && prefix[0] <= 'Z') {
+		return 0, 0, errors.New("invalid DOWNLOAD format")
+	}
+	i += q + 1
+	q = bytes.IndexByte(data[i:], '"')
+	if q < 0 {
+		return short()
+	}
+	if bytes.IndexAny(data[i:i+q], "\r\n") >= 0 {
+		return 0, 0, errors.New("invalid DOWNLOAD format")
+	}
+	i += q + 1
+	if i == len(data) {
+		return short()
+	}
+	if data[i] != ',' {
+		// No size: a program download.
+		return 0, 0, errors.New("not a DOWNLOAD of a file")
+	}
+	i++
+	j := i
+	for j < len(data) && data[j] >= '0' && data[j] <= '9' {
+		j++
+	}
+	if j == len(data) {
+		return short()
+	}
+	if j == i || data[j] != ',' {
+		return 0, 0, errors.New("invalid DOWNLOAD format")
+	}
+	size, err = strconv.Atoi(string(data[i:j]))
+	if err != nil {
+		return 0, 0, errors.New("invalid DOWNLOAD size")
+	}
+	return j + 1, size, nil
+}
+
+// scanCommands calls fn for every command in program until fn returns false.
+func scanCommands(program []byte, fn func(cmd []byte) bool) error {
+	for len(program) > 0 {
+		advance, token, err := splitCommand(program, true)
+		if err != nil {
+			return err
+		}
+		program = program[advance:]
+		if token != nil && !fn(token) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// splitWord splits s at the first run of spaces.
+func splitWord(s []byte) (word, rest []byte) {
+	s = bytes.TrimLeft(s, " \t")
+	i := bytes.IndexAny(s, " \t")
+	if i < 0 {
+		return s, nil
+	}
+	return s[:i], bytes.TrimLeft(s[i:], " \t")
+}
+
+// splitArgs splits a command's arguments at commas outside double quotes and
+// trims the spaces around each one.
+func splitArgs(args []byte) [][]byte {
+	var res [][]byte
+	quoted := false
+	start := 0
+	for i, b := range args {
+		switch {
+		case b == '"':
+			quoted = !quoted
+		case b == ',' && !quoted:
+			res = append(res, bytes.TrimSpace(args[start:i]))
+			start = i + 1
+		}
+	}
+	return append(res, bytes.TrimSpace(args[start:]))
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
@@ -0,0 +1,215 @@
+package tspl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrSessionClosed is returned by SessionManager.Print after Close.
+var ErrSessionClosed = errors.New("session manager closed")
+
+const (
+	sessionAttempts = 3
+	sessionBackoff  = 100 * time.Millisecond
+	sessionTimeout  = 10 * time.Second
+)
+
+// statusQuery is <ESC>!?, which makes the printer answer with one status
+// byte. Bit 5 means "printing"; any other bit set reports a fault such as an
+// open head, a paper jam or missing media.
+var statusQuery = []byte{0x1b, '!', '?'}
+
+const statusPrinting = 0x20
+
+// SessionManager sends jobs to a networked printer over raw TCP, keeping up
+// to maxConns connections open for reuse. It is safe for concurrent use.
+type SessionManager struct {
+	addr     string
+	slots    chan struct{}
+	idle     chan net.Conn
+	attempts int
+	backoff  time.Duration
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewSessionManager creates a manager for the printer at printerIP:port,
+// usually port 9100. At most maxConns jobs are in flight at once.
+func NewSessionManager(printerIP string, port int, maxConns int) *SessionManager {
+	return newSessionManager(net.JoinHostPort(printerIP, strconv.Itoa(port)), maxConns)
+}
+
+func newSessionManager(addr string, maxConns int) *SessionManager {
+	if maxConns <= 0 {
+		maxConns = 1
+	}
+	return &SessionManager{
+		addr:     addr,
+		slots:    make(chan struct{}, maxConns),
+		idle:     make(chan net.Conn, maxConns),
+		attempts: sessionAttempts,
+		backoff:  sessionBackoff,
+	}
+}
+
+// WithRetry sets how many times Print tries to deliver a job, at least once,
+// and the back-off before the first retry, which doubles with every further
+// retry. The defaults are 3 attempts and 100ms. Only failures before any of
+// the job was written are retried, so a job is never sent twice. It must be
+// called before the manager is used and returns m for chaining.
+func (m *SessionManager) WithRetry(maxAttempts int, backoff time.Duration) *SessionManager {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	m.attempts, m.backoff = maxAttempts, backoff
+	return m
+}
+
+// PrintError is returned by SessionManager.Print when a job failed: either
+// it was written and the printer reported a fault or stopped answering, or
+// every attempt to deliver it failed.
+type PrintError struct {
+	// Status is the fault status byte the printer answered with, or 0 if it
+	// did not answer.
+	Status byte
+	// Fingerprint is the SHA-256 of the job's BITMAP commands. For a job
+	// with a single BITMAP it matches the fingerprint of EncodeWithReceipt.
+	Fingerprint [32]byte
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e *PrintError) Error() string {
+	return fmt.Sprintf("print job %x failed: %v", e.Fingerprint[:4], e.Err)
+}
+
+func (e *PrintError) Unwrap() error { return e.Err }
+
+// bitmapFingerprint hashes the BITMAP commands of doc.
+func bitmapFingerprint(doc []byte) [32]byte {
+	h := sha256.New()
+	scanCommands(doc, func(cmd []byte) bool {
+		if bytes.HasPrefix(cmd, []byte(CmdBitmap)) {
+			h.Write(cmd)
+		}
+		return true
+	})
+	var sum [32]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// Print sends doc and waits for the printer's status byte. A failure before
+// any of doc was written, such as a refused connection, is retried with
+// exponential back-off, as set by WithRetry. Once bytes have gone out the
+// printer may already hold or have printed the job, so a later network error
+// or a fault status, such as paper out or an open head, is not retried but
+// returned at once, as a *PrintError like the one returned when every
+// attempt failed.
+func (m *SessionManager) Print(doc []byte) error {
+	m.slots <- struct{}{}
+	defer func() { <-m.slots }()
+
+	var err error
+	backoff := m.backoff
+	for attempt := 0; attempt < m.attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var conn net.Conn
+		if conn, err = m.get(); err != nil {
+			if err == ErrSessionClosed {
+				return err
+			}
+			continue
+		}
+		var status byte
+		var written bool
+		status, written, err = send(conn, doc)
+		if err == nil && status&^statusPrinting != 0 {
+			err = fmt.Errorf("printer status %#02x", status)
+		}
+		if err == nil {
+			m.put(conn)
+			return nil
+		}
+		conn.Close()
+		if written {
+			return &PrintError{Status: status, Fingerprint: bitmapFingerprint(doc), Err: err}
+		}
+	}
+	return &PrintError{Fingerprint: bitmapFingerprint(doc), Err: err}
+}
+
+// send writes doc followed by a status query and reads the reply. written
+// reports whether any of doc may have reached the printer.
+func send(conn net.Conn, doc []byte) (status byte, written bool, err error) {
+	if err := conn.SetDeadline(time.Now().Add(sessionTimeout)); err != nil {
+		return 0, false, err
+	}
+	if n, err := conn.Write(doc); err != nil {
+		return 0, n > 0, err
+	}
+	if _, err := conn.Write(statusQuery); err != nil {
+		return 0, true, err
+	}
+	var reply [1]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return 0, true, err
+	}
+	return reply[0], true, nil
+}
+
+func (m *SessionManager) get() (net.Conn, error) {
+	m.mu.Lock()
+	closed := m.closed
+	m.mu.Unlock()
+	if closed {
+		return nil, ErrSessionClosed
+	}
+	select {
+	case conn := <-m.idle:
+		return conn, nil
+	default:
+		return net.DialTimeout("tcp", m.addr, sessionTimeout)
+	}
+}
+
+func (m *SessionManager) put(conn net.Conn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		conn.Close()
+		return
+	}
+	select {
+	case m.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+// Close closes the idle connections. Jobs in flight finish, after which
+// their connections are closed too.
+func (m *SessionManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for {
+		select {
+		case conn := <-m.idle:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package tspl
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePrinter accepts raw TCP jobs, records every document it receives and
+// answers each status query with status, unless hangUp is set, in which case
+// it closes the connection instead.
+type fakePrinter struct {
+	ln     net.Listener
+	status byte
+	hangUp bool
+
+	mu    sync.Mutex
+	docs  [][]byte
+	conns int
+}
+
+func newFakePrinter(t *testing.T, status byte, hangUp bool) *fakePrinter {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &fakePrinter{ln: ln, status: status, hangUp: hangUp}
+	t.Cleanup(func() { ln.Close() })
+	go p.serve()
+	return p
+}
+
+func (p *fakePrinter) serve() {
+	for {
+		conn, err := p.ln.Accept()
+		if err != nil {
+			return
+		}
+		p.mu.Lock()
+		p.conns++
+		p.mu.Unlock()
+		go p.handle(conn)
+	}
+}
+
+func (p *fakePrinter) handle(conn net.Conn) {
+	defer conn.Close()
+	var buf []byte
+	chunk := make([]byte, 4096)
+	for {
+		n, err := conn.Read(chunk)
+		if err != nil {
+			return
+		}
+		buf = append(buf, chunk[:n]...)
+		for {
+			i := bytes.Index(buf, statusQuery)
+			if i < 0 {
+				break
+			}
+			p.mu.Lock()
+			p.docs = append(p.docs, append([]byte(nil), buf[:i]...))
+			p.mu.Unlock()
+			buf = buf[i+len(statusQuery):]
+			if p.hangUp {
+				return
+			}
+			if _, err := conn.Write([]byte{p.status}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (p *fakePrinter) received() (docs, conns int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.docs), p.conns
+}
+
+var testDoc = []byte("CLS\r\nPRINT 1,1\r\n")
+
+func TestSessionPrintReusesConnection(t *testing.T) {
+	p := newFakePrinter(t, statusPrinting, false)
+	m := newSessionManager(p.ln.Addr().String(), 1)
+	defer m.Close()
+	for i := 0; i < 3; i++ {
+		if err := m.Print(testDoc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if docs, conns := p.received(); docs != 3 || conns != 1 {
+		t.Fatalf("got %d documents over %d connections, want 3 over 1", docs, conns)
+	}
+}
+
+func TestSessionPrintFaultIsNotResent(t *testing.T) {
+	const paperOut = 0x04
+	p := newFakePrinter(t, paperOut, false)
+	m := newSessionManager(p.ln.Addr().String(), 1).WithRetry(3, time.Millisecond)
+	defer m.Close()
+	err := m.Print(testDoc)
+	var pe *PrintError
+	if !errors.As(err, &pe) || pe.Status != paperOut {
+		t.Fatalf("Print = %v, want a *PrintError with status %#02x", err, paperOut)
+	}
+	if docs, _ := p.received(); docs != 1 {
+		t.Fatalf("printer received the job %d times, want once", docs)
+	}
+}
+
+func TestSessionPrintLostReplyIsNotResent(t *testing.T) {
+	p := newFakePrinter(t, 0, true)
+	m := newSessionManager(p.ln.Addr().String(), 1).WithRetry(3, time.Millisecond)
+	defer m.Close()
+	var pe *PrintError
+	if err := m.Print(testDoc); !errors.As(err, &pe) {
+		t.Fatalf("Print = %v, want a *PrintError", err)
+	}
+	if docs, _ := p.received(); docs != 1 {
+		t.Fatalf("printer received the job %d times, want once", docs)
+	}
+}
+
+func TestSessionRetriesUndeliveredJob(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// Nothing listens, so every attempt fails before writing.
+	start := time.Now()
+	m := newSessionManager(addr, 1).WithRetry(3, 10*time.Millisecond)
+	var pe *PrintError
+	if err := m.Print(testDoc); !errors.As(err, &pe) || pe.Status != 0 {
+		t.Fatalf("Print = %v, want a *PrintError without status", err)
+	}
+	if d := time.Since(start); d < 30*time.Millisecond {
+		t.Fatalf("Print gave up after %v, want back-offs of 10ms and 20ms", d)
+	}
+
+	// A printer that comes up during the back-off gets the job once.
+	m = newSessionManager(addr, 1).WithRetry(5, 20*time.Millisecond)
+	defer m.Close()
+	done := make(chan error, 1)
+	go func() { done <- m.Print(testDoc) }()
+	time.Sleep(5 * time.Millisecond)
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("cannot listen on %s again: %v", addr, err)
+	}
+	p := &fakePrinter{ln: ln, status: 0}
+	defer ln.Close()
+	go p.serve()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if docs, _ := p.received(); docs != 1 {
+		t.Fatalf("printer received the job %d times, want once", docs)
+	}
+}
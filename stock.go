@@ -0,0 +1,168 @@
+package tspl
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// labelStock is a standard label size in mm and how far a SIZE may be off it
+// and still count as exact.
+type labelStock struct {
+	w, h, tolerance float64
+}
+
+// labelStocks lists common label stocks by name. Inch sizes are named as
+// usually sold, e.g. "4x6" is 4 x 6 inches.
+var labelStocks = map[string]labelStock{
+	"4x6":       {101.6, 152.4, 0.5},
+	"4x4":       {101.6, 101.6, 0.5},
+	"4x3":       {101.6, 76.2, 0.5},
+	"4x2":       {101.6, 50.8, 0.5},
+	"3x2":       {76.2, 50.8, 0.5},
+	"3x1":       {76.2, 25.4, 0.5},
+	"2x1":       {50.8, 25.4, 0.3},
+	"2.25x1.25": {57.15, 31.75, 0.3},
+	"100x150mm": {100, 150, 0.5},
+	"100x100mm": {100, 100, 0.5},
+	"60x40mm":   {60, 40, 0.3},
+	"50x30mm":   {50, 30, 0.3},
+	"40x30mm":   {40, 30, 0.3},
+	"62x29mm":   {62, 29, 0.3},
+	"A4":        {210, 297, 1},
+	"A5":        {148, 210, 1},
+	"A6":        {105, 148, 0.5},
+	"Letter":    {215.9, 279.4, 1},
+}
+
+// LabelSizeWarning is returned by ValidateLabelSize for a size close to, but
+// not exactly, its stock. Callers that accept near misses can check for it
+// with errors.As and carry on.
+type LabelSizeWarning struct {
+	Stock         string
+	Width, Height float64
+}
+
+func (e *LabelSizeWarning) Error() string {
+	s := labelStocks[e.Stock]
+	return fmt.Sprintf("label size %.1f x %.1f mm is close to but not %s (%.2f x %.2f mm)",
+		e.Width, e.Height, e.Stock, s.w, s.h)
+}
+
+// ValidateLabelSize checks a w x h mm label size against the named stock, in
+// either orientation. It returns nil for a size within the stock's tolerance,
+// a *LabelSizeWarning for one within 5% of it and an error otherwise.
+func ValidateLabelSize(w, h float64, stockType string) error {
+	s, ok := labelStocks[stockType]
+	if !ok {
+		return fmt.Errorf("unknown label stock %q", stockType)
+	}
+	if s.w != s.h && math.Abs(w-s.h)+math.Abs(h-s.w) < math.Abs(w-s.w)+math.Abs(h-s.h) {
+		w, h = h, w
+	}
+	dw, dh := math.Abs(w-s.w), math.Abs(h-s.h)
+	switch {
+	case dw <= s.tolerance && dh <= s.tolerance:
+		return nil
+	case dw <= s.w*0.05 && dh <= s.h*0.05:
+		return &LabelSizeWarning{Stock: stockType, Width: w, Height: h}
+	}
+	return fmt.Errorf("label size %.1f x %.1f mm does not match %s (%.2f x %.2f mm)", w, h, stockType, s.w, s.h)
+}
+
+// NearestStandardSize returns the stock closest to a w x h mm label, in either
+// orientation, and the distance between the two sizes in mm.
+func NearestStandardSize(w, h float64) (string, float64) {
+	best, bestDist := "", math.Inf(1)
+	for name, s := range labelStocks {
+		d := math.Min(math.Hypot(w-s.w, h-s.h), math.Hypot(w-s.h, h-s.w))
+		// Ties go to the name sorting first, so the result is stable.
+		if d < bestDist || d == bestDist && name < best {
+			best, bestDist = name, d
+		}
+	}
+	return best, bestDist
+}
+
+// ParsePaperType parses a paper description such as "4x6in@203dpi",
+// "100x150mm@12dpm" or "A5@203dpi" into its size in mm and its resolution in
+// dots per mm. The size is either the name of a standard stock, as known to
+// ValidateLabelSize and matched ignoring case, or W x H followed by "mm" or
+// "in". The resolution after the @ is optional and given in "dpi", rounded to
+// whole dots per mm, or "dpm"; without it dpm is 0, for the default of 8.
+func ParsePaperType(s string) (w, h float64, dpm int, err error) {
+	size, res, hasRes := strings.Cut(strings.TrimSpace(s), "@")
+	if hasRes {
+		if dpm, err = parseResolution(res); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	for name, st := range labelStocks {
+		if strings.EqualFold(size, name) {
+			return st.w, st.h, dpm, nil
+		}
+	}
+	scale := 1.0
+	switch lower := strings.ToLower(size); {
+	case strings.HasSuffix(lower, "mm"):
+		size = size[:len(size)-2]
+	case strings.HasSuffix(lower, "in"):
+		size, scale = size[:len(size)-2], 25.4
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid paper type %q: unknown stock or size without mm or in", s)
+	}
+	ws, hs, ok := strings.Cut(strings.ToLower(size), "x")
+	if ok {
+		w, err = strconv.ParseFloat(strings.TrimSpace(ws), 64)
+	}
+	if ok && err == nil {
+		h, err = strconv.ParseFloat(strings.TrimSpace(hs), 64)
+	}
+	if !ok || err != nil || !(w > 0 && h > 0) || math.IsInf(w, 0) || math.IsInf(h, 0) {
+		return 0, 0, 0, fmt.Errorf("invalid paper size in %q", s)
+	}
+	return w * scale, h * scale, dpm, nil
+}
+
+// parseResolution parses the resolution part of a paper type into dots per
+// mm.
+func parseResolution(res string) (int, error) {
+	lower := strings.ToLower(strings.TrimSpace(res))
+	num, isDPI := strings.CutSuffix(lower, "dpi")
+	if !isDPI {
+		var ok bool
+		if num, ok = strings.CutSuffix(lower, "dpm"); !ok {
+			return 0, fmt.Errorf("invalid resolution %q: want dpi or dpm", res)
+		}
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v != math.Trunc(v) || v < 1 {
+		return 0, fmt.Errorf("invalid resolution %q", res)
+	}
+	if !isDPI && v > maxDPM {
+		return 0, fmt.Errorf("resolution %q is too high for dots per millimetre: "+
+			"use %ddpi or %ddpm", res, int(v), int(math.Round(v/25.4)))
+	}
+	dpm := math.Round(v / 25.4)
+	if !isDPI {
+		dpm = v
+	}
+	if dpm < 1 || dpm > maxDPM {
+		return 0, fmt.Errorf("invalid resolution %q", res)
+	}
+	return int(dpm), nil
+}
+
+// EncodeForPaper is like Encode but takes the label size and resolution from
+// a paper type, as parsed by ParsePaperType, rounding the size to whole dots.
+func (t *Driver) EncodeForPaper(paperType string, img image.Image, opt Options) ([]byte, error) {
+	w, h, dpm, err := ParsePaperType(paperType)
+	if err != nil {
+		return nil, err
+	}
+	d := float64(cmp.Or(dpm, 8))
+	return t.Encode(int(math.Round(w*d)), int(math.Round(h*d)), dpm, img, opt)
+}
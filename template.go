@@ -0,0 +1,63 @@
+package tspl
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"text/template"
+)
+
+// TemplateCache holds compiled TSPL label templates by name. It is safe for
+// concurrent use.
+//
+// Patterns use text/template syntax, with variables referenced as {{.name}}.
+// Referencing a variable missing from vars is an error. Variable values are
+// escaped with EscapeTSPL, so they can be placed in quoted arguments as is and
+// cannot break out of them.
+type TemplateCache struct {
+	mu        sync.RWMutex
+	templates map[string]*template.Template
+}
+
+func NewTemplateCache() *TemplateCache {
+	return &TemplateCache{templates: make(map[string]*template.Template)}
+}
+
+// Compile parses pattern and stores it under name, replacing any previous
+// template of the same name.
+func (c *TemplateCache) Compile(name, pattern string) error {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.templates[name] = tmpl
+	c.mu.Unlock()
+	return nil
+}
+
+// Execute renders the template stored under name with vars.
+func (c *TemplateCache) Execute(name string, vars map[string]string) ([]byte, error) {
+	c.mu.RLock()
+	tmpl, ok := c.templates[name]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("template %q not found", name)
+	}
+	escaped := make(map[string]string, len(vars))
+	for k, v := range vars {
+		escaped[k] = EscapeTSPL(v)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, escaped); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Remove evicts the template stored under name, if any.
+func (c *TemplateCache) Remove(name string) {
+	c.mu.Lock()
+	delete(c.templates, name)
+	c.mu.Unlock()
+}
@@ -3,19 +3,137 @@ package tspl
 import (
 	"bytes"
 	"cmp"
+	"context"
 	"errors"
 	"fmt"
 	"image"
+	"io"
+	"math"
+	"strings"
 
 	"github.com/haxii/tspl/bin-img"
 )
 
 var DefaultDriver = &Driver{}
 
-type Driver struct{}
+type Driver struct {
+	// Capabilities, when set, makes the Encode methods fail with
+	// ErrUnsupportedCommand for options and Extra commands, such as QRCODE,
+	// the model cannot honour.
+	Capabilities *PrinterCapabilities
+	// InkIsOn flips the meaning of the bits of the *bin_img.Binary images
+	// the driver takes and returns: on is ink and off is paper, instead of the
+	// default on for white. Other image types are unaffected; their dark
+	// pixels always print.
+	InkIsOn bool
+	// MaxWidthMM and MaxHeightMM, when not 0, make the Encode methods fail
+	// for labels wider or longer than this many millimetres, a guard against
+	// a bad size, such as a unit mix-up, feeding a whole roll of media.
+	MaxWidthMM, MaxHeightMM float64
+}
 
 type Options struct {
 	Peel bool `json:"peel"`
+	// WaitForTake makes the printer hold each label at the peel bar until it
+	// is taken, with a label-taken sensor, before printing the next one. It
+	// implies Peel: TSPL has no separate wait command, SET PEEL ON is what
+	// arms the sensor, so every label of PRINT's sets and copies pauses.
+	WaitForTake bool `json:"wait_for_take,omitempty"`
+	// Dialect selects the command spellings used by Header.
+	Dialect Dialect `json:"dialect"`
+	// Offset, when set, emits OFFSET to move the tear/peel stop position, in
+	// mm. It must be within ±25.4 mm.
+	Offset *float64 `json:"offset,omitempty"`
+	// Shift, when set, emits SHIFT to move the print vertically, in dots. It
+	// must be within one inch, i.e. ±25.4*dpm dots.
+	Shift *int `json:"shift,omitempty"`
+	// ClearRegion, when set, clears only this region of the image buffer, in
+	// dots, instead of all of it. Not every firmware supports a regional CLS.
+	ClearRegion *image.Rectangle `json:"clear_region,omitempty"`
+	// NoClear omits CLS so the job draws over whatever is already in the
+	// printer's image buffer, e.g. a background sent by a previous job. The
+	// buffer only carries over between jobs when CLS is left out.
+	NoClear bool `json:"no_clear,omitempty"`
+	// Extra holds raw commands, such as BOX, TEXT or SOUND, emitted after the
+	// bitmap and before PRINT. Each gets a \r\n appended.
+	Extra []string `json:"extra,omitempty"`
+	// MaxBitmapRows, when positive, splits the bitmap into stacked BITMAP
+	// commands of at most this many rows, for firmwares that cap the size of
+	// a single BITMAP.
+	MaxBitmapRows int `json:"max_bitmap_rows,omitempty"`
+	// Unit selects the unit of the SIZE command, millimetres by default.
+	Unit SizeUnit `json:"unit,omitempty"`
+	// Cutter emits SET CUTTER ON to cut after every label instead of OFF.
+	Cutter bool `json:"cutter,omitempty"`
+	// HeadClose emits SET HEAD_CLOSE 1, which some TSC applicator models need
+	// to lock the print head; without it they fault after the first job.
+	HeadClose bool `json:"head_close,omitempty"`
+	// Speed, when set, emits SPEED, in inches per second.
+	Speed *float64 `json:"speed,omitempty"`
+	// Density, when set, emits DENSITY, the print darkness from 0 to 15.
+	Density *int `json:"density,omitempty"`
+	// Sets and Copies are the arguments of PRINT: Sets label sets of Copies
+	// copies each. Zero means 1.
+	Sets   int `json:"sets,omitempty"`
+	Copies int `json:"copies,omitempty"`
+	// PrintOffset resumes a partly printed batch: the first PrintOffset of
+	// the Sets label sets are left out, as by Driver.PrintOffset. It must be
+	// less than Sets.
+	PrintOffset int `json:"print_offset,omitempty"`
+	// Delay, when positive, pauses this many milliseconds between label
+	// sets, e.g. to let an applicator settle: each set gets its own PRINT
+	// 1,Copies with a DELAY between them. For a pause after every label,
+	// print Sets labels of one copy each. It must be at most maxDelay.
+	Delay int `json:"delay,omitempty"`
+}
+
+// maxDelay is the longest Delay accepted, in milliseconds. Longer pauses are
+// better left to the host than held in the printer's buffer.
+const maxDelay = 60000
+
+// maxDPM is the largest dots-per-mm value accepted. Real heads top out at
+// 24 dots/mm (600 dpi), so anything above is almost certainly a dpi value.
+const maxDPM = 50
+
+// Validate checks opt for a label printed at dpm dots per millimetre (8 if
+// zero), as the Encode methods do: the ranges of Offset, Shift, Delay and
+// the other settings. Header does not check them; HeaderStrict does.
+func (opt Options) Validate(dpm int) error {
+	if dpm < 0 {
+		return fmt.Errorf("invalid dpm %d", dpm)
+	}
+	if dpm > maxDPM {
+		return fmt.Errorf("dpm %d is dots per millimetre, not dots per inch: "+
+			"use %d for a %d dpi printer", dpm, int(math.Round(float64(dpm)/25.4)), dpm)
+	}
+	if !opt.Unit.valid() {
+		return fmt.Errorf("invalid size unit %v", opt.Unit)
+	}
+	if opt.Offset != nil && (math.IsNaN(*opt.Offset) || math.Abs(*opt.Offset) > 25.4) {
+		return fmt.Errorf("offset %v mm out of range", *opt.Offset)
+	}
+	if opt.Shift != nil {
+		limit := int(25.4 * float64(cmp.Or(dpm, 8)))
+		if *opt.Shift < -limit || *opt.Shift > limit {
+			return fmt.Errorf("shift %d dots out of range ±%d", *opt.Shift, limit)
+		}
+	}
+	if opt.Speed != nil && !(*opt.Speed > 0) {
+		return fmt.Errorf("invalid speed %v", *opt.Speed)
+	}
+	if opt.Density != nil && (*opt.Density < 0 || *opt.Density > 15) {
+		return fmt.Errorf("density %d out of range 0 to 15", *opt.Density)
+	}
+	if opt.Sets < 0 || opt.Copies < 0 {
+		return fmt.Errorf("invalid PRINT count %d,%d", opt.Sets, opt.Copies)
+	}
+	if sets := cmp.Or(opt.Sets, 1); opt.PrintOffset < 0 || opt.PrintOffset >= sets {
+		return fmt.Errorf("print offset %d out of range 0 to %d", opt.PrintOffset, sets-1)
+	}
+	if opt.Delay < 0 || opt.Delay > maxDelay {
+		return fmt.Errorf("delay %d ms out of range 0 to %d", opt.Delay, maxDelay)
+	}
+	return nil
 }
 
 var defaultOptions Options
@@ -24,48 +142,335 @@ func DefaultOptions() Options {
 	return defaultOptions
 }
 
+// Header returns the setup commands for a w x h dot label printed at dpm
+// dots per millimetre (8 if zero). It does not validate its arguments, so an
+// out of range or NaN Offset is emitted as is; the Encode methods validate
+// them. For a header built by hand, use HeaderStrict.
 func (t *Driver) Header(w, h, dpm int, opt Options) string {
+	return t.header(w, h, dpm, opt, false)
+}
+
+// HeaderStrict is like Header but first checks opt, the label size and the
+// model as the Encode methods do: it fails for invalid options, for labels
+// beyond MaxWidthMM or MaxHeightMM and for settings Capabilities rules out.
+func (t *Driver) HeaderStrict(w, h, dpm int, opt Options) (string, error) {
+	if err := t.checkOptions(w, h, dpm, opt); err != nil {
+		return "", err
+	}
+	return t.Header(w, h, dpm, opt), nil
+}
+
+// AutoHeader is like Header but takes the label size from img's bounds,
+// rounded up to the next 0.1 mm (or 0.01 inch) so the label is never smaller
+// than the image.
+func (t *Driver) AutoHeader(img image.Image, dpm int, opt Options) string {
 	dpm = cmp.Or(dpm, 8)
-	peel := "OFF"
-	if opt.Peel {
-		peel = "ON"
+	b := img.Bounds()
+	u := opt.Unit
+	return t.writeHeader(u.roundUp(u.fromDots(b.Dx(), dpm)), u.roundUp(u.fromDots(b.Dy(), dpm)), opt, false)
+}
+
+// header builds the label header; gapless adds GAP 0,0 for continuous media.
+func (t *Driver) header(w, h, dpm int, opt Options, gapless bool) string {
+	dpm = cmp.Or(dpm, 8)
+	return t.writeHeader(opt.Unit.fromDots(w, dpm), opt.Unit.fromDots(h, dpm), opt, gapless)
+}
+
+// writeHeader builds the label header for a w x h label measured in
+// opt.Unit.
+func (t *Driver) writeHeader(w, h float64, opt Options, gapless bool) string {
+	var b strings.Builder
+	set := func(key Keyword, val any) {
+		fmt.Fprintf(&b, "%s %s %v\r\n", CmdSet, key, val)
+	}
+	set(SetCutter, onOff(opt.Cutter))
+	set(opt.Dialect.partialCutter(), onOff(false))
+	set(SetPeel, onOff(opt.peel()))
+	if opt.HeadClose {
+		set(SetHeadClose, 1)
+	}
+	if opt.Offset != nil {
+		fmt.Fprintf(&b, "%s %.1f mm\r\n", CmdOffset, *opt.Offset)
+	}
+	if opt.Shift != nil {
+		fmt.Fprintf(&b, "%s %d\r\n", CmdShift, *opt.Shift)
+	}
+	fmt.Fprintf(&b, "%s %s\r\n", CmdSize, opt.Unit.size(w, h))
+	if gapless {
+		fmt.Fprintf(&b, "%s 0,0\r\n", CmdGap)
 	}
-	return fmt.Sprintf("SET CUTTER OFF\r\nSET PARTICAL_CUTTER OFF\r\n"+
-		"SET PEEL %s\r\nSIZE %.1f mm, %.1f mm\r\nCLS\r\n", peel,
-		float64(w)/float64(dpm), float64(h)/float64(dpm))
+	if opt.Speed != nil {
+		fmt.Fprintf(&b, "%s %g\r\n", CmdSpeed, *opt.Speed)
+	}
+	if opt.Density != nil {
+		fmt.Fprintf(&b, "%s %d\r\n", CmdDensity, *opt.Density)
+	}
+	switch r := opt.ClearRegion; {
+	case opt.NoClear:
+	case r != nil:
+		b.WriteString(t.ClsRegion(r.Min.X, r.Min.Y, r.Dx(), r.Dy()))
+	default:
+		fmt.Fprintf(&b, "%s\r\n", CmdCls)
+	}
+	return b.String()
+}
+
+// peel reports whether the label is peeled off, for Peel or WaitForTake.
+func (opt Options) peel() bool {
+	return opt.Peel || opt.WaitForTake
+}
+
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// ClsRegion returns a CLS command that clears only the given region of the
+// image buffer, in dots.
+func (t *Driver) ClsRegion(x, y, width, height int) string {
+	return fmt.Sprintf("%s %d,%d,%d,%d\r\n", CmdCls, x, y, width, height)
+}
+
+// Calibrate returns GAPDETECT, which feeds labels to measure the label
+// length and gap of newly loaded media. Not every model supports it; see
+// AutoSense.
+func (t *Driver) Calibrate() string {
+	return fmt.Sprintf("%s\r\n", CmdGapDetect)
+}
+
+// AutoSense returns AUTODETECT, which calibrates the media sensor and
+// measures the label length and gap.
+func (t *Driver) AutoSense() string {
+	return fmt.Sprintf("%s\r\n", CmdAutoDetect)
+}
+
+// Formfeed returns FORMFEED, which feeds one label.
+func (t *Driver) Formfeed() string {
+	return fmt.Sprintf("%s\r\n", CmdFormFeed)
+}
+
+// Delay returns DELAY, which pauses the printer for ms milliseconds before it
+// runs the next command. It does not validate ms; Options.Delay is checked by
+// the Encode methods.
+func (t *Driver) Delay(ms int) string {
+	return fmt.Sprintf("%s %d\r\n", CmdDelay, ms)
+}
+
+// PrintOffset returns the PRINT command that resumes a batch of sets label
+// sets of copies copies each after its first offset sets were printed. TSPL's
+// PRINT m[,n] has no skip argument, so the skipped sets are simply left out;
+// printer-side counters must be reset to their resume values separately. It
+// returns "" if nothing is left to print.
+func (t *Driver) PrintOffset(copies, sets, offset int) string {
+	if offset < 0 {
+		offset = 0
+	}
+	if sets-offset <= 0 || copies <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s %d,%d\r\n", CmdPrint, sets-offset, copies)
 }
 
 func (t *Driver) Encode(w, h, dpm int, img image.Image, opt Options) ([]byte, error) {
-	_, bitmap, err := t.Image2Bytes(img)
+	return t.EncodeContext(context.Background(), w, h, dpm, img, opt)
+}
+
+// EncodeContext is like Encode but stops early and returns ctx.Err() once ctx
+// is done, checking it every few rows while the image is thresholded and the
+// bitmap is packed.
+func (t *Driver) EncodeContext(ctx context.Context, w, h, dpm int, img image.Image, opt Options) ([]byte, error) {
+	if err := checkImageFits(w, img); err != nil {
+		return nil, err
+	}
+	_, bitmap, err := t.image2Bytes(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	return t.EncodeWithBitmap(w, h, dpm, bitmap, opt)
+}
+
+// EncodeWithBitmap is like Encode but takes an already encoded BITMAP command,
+// as returned by Image2Bytes or OverlayBinary, so the same bitmap can be reused
+// across labels without encoding it again.
+func (t *Driver) EncodeWithBitmap(w, h, dpm int, bitmapCmd []byte, opt Options) ([]byte, error) {
+	return t.encodeWithBitmap(w, h, dpm, bitmapCmd, opt, false)
+}
+
+func (t *Driver) encodeWithBitmap(w, h, dpm int, bitmapCmd []byte, opt Options, gapless bool) ([]byte, error) {
+	if !bytes.HasPrefix(bitmapCmd, []byte(CmdBitmap)) {
+		return nil, errors.New("not a BITMAP command")
+	}
+	if err := t.checkOptions(w, h, dpm, opt); err != nil {
+		return nil, err
+	}
+	hdr, err := t.ParseBitmapHeader(bitmapCmd)
 	if err != nil {
 		return nil, err
 	}
-	header := t.Header(w, h, dpm, opt)
-	tail := "PRINT 1,1\r\n"
-	l := len(header) + len(bitmap) + len(tail)
+	if err := checkFits(w, hdr); err != nil {
+		return nil, err
+	}
+	if opt.MaxBitmapRows > 0 {
+		split, err := t.SplitBitmap(bitmapCmd, opt.MaxBitmapRows)
+		if err != nil {
+			return nil, err
+		}
+		bitmapCmd = split
+	}
+	return t.assemble(w, h, dpm, bitmapCmd, opt, gapless), nil
+}
+
+// checkFits checks that the bitmap of h fits across a label w dots wide. The
+// label size comes from the caller while the BITMAP width comes from the
+// image, so a mismatch would otherwise print cut off. A BITMAP command only
+// gives its width in bytes, so up to 7 dots of padding in the last byte of a
+// row may hang over the edge; where the image is at hand, checkImageFits
+// checks its exact width instead.
+func checkFits(w int, h *BitmapHeader) error {
+	if h.X+h.Width > w+7 {
+		return fmt.Errorf("bitmap %d dots wide at x=%d does not fit on a label %d dots wide",
+			h.Width, h.X, w)
+	}
+	return nil
+}
+
+// checkImageFits checks that img is no wider than a label w dots wide.
+func checkImageFits(w int, img image.Image) error {
+	if dx := img.Bounds().Dx(); dx > w {
+		return fmt.Errorf("image %d dots wide does not fit on a label %d dots wide", dx, w)
+	}
+	return nil
+}
+
+// CheckLabelSize checks a w x h dot label printed at dpm dots per
+// millimetre (8 if zero) against MaxWidthMM and MaxHeightMM.
+func (t *Driver) CheckLabelSize(w, h, dpm int) error {
+	dpm = cmp.Or(dpm, 8)
+	if mm := float64(w) / float64(dpm); t.MaxWidthMM > 0 && mm > t.MaxWidthMM {
+		return fmt.Errorf("label width %g mm exceeds the maximum of %g mm", mm, t.MaxWidthMM)
+	}
+	if mm := float64(h) / float64(dpm); t.MaxHeightMM > 0 && mm > t.MaxHeightMM {
+		return fmt.Errorf("label height %g mm exceeds the maximum of %g mm", mm, t.MaxHeightMM)
+	}
+	return nil
+}
+
+// checkOptions validates opt and the label size and, if Capabilities is set,
+// checks that the model can honour it.
+func (t *Driver) checkOptions(w, h, dpm int, opt Options) error {
+	if err := opt.Validate(dpm); err != nil {
+		return err
+	}
+	if err := t.CheckLabelSize(w, h, dpm); err != nil {
+		return err
+	}
+	c := t.Capabilities
+	if c == nil {
+		return nil
+	}
+	if opt.peel() && !c.Supports(string(SetPeel)) {
+		return fmt.Errorf("%w: PEEL on %s", ErrUnsupportedCommand, c.Model)
+	}
+	if opt.Cutter && !c.Supports(string(SetCutter)) {
+		return fmt.Errorf("%w: CUTTER on %s", ErrUnsupportedCommand, c.Model)
+	}
+	if opt.Speed != nil && c.MaxSpeedInchPerSec > 0 && *opt.Speed > c.MaxSpeedInchPerSec {
+		return fmt.Errorf("%w: SPEED %g on %s", ErrUnsupportedCommand, *opt.Speed, c.Model)
+	}
+	if opt.Density != nil && c.MaxDensity > 0 && *opt.Density > c.MaxDensity {
+		return fmt.Errorf("%w: DENSITY %d on %s", ErrUnsupportedCommand, *opt.Density, c.Model)
+	}
+	for _, cmd := range opt.Extra {
+		if name, _ := splitWord([]byte(cmd)); !c.Supports(string(name)) {
+			return fmt.Errorf("%w: %s on %s", ErrUnsupportedCommand, name, c.Model)
+		}
+	}
+	return nil
+}
+
+// assemble wraps body, the label's drawing commands, in the header, the Extra
+// commands and PRINT. opt must have passed checkOptions.
+func (t *Driver) assemble(w, h, dpm int, body []byte, opt Options, gapless bool) []byte {
+	header := t.header(w, h, dpm, opt, gapless)
+	tail := t.printTail(opt)
+	l := len(header) + len(body) + len(tail)
+	for _, line := range opt.Extra {
+		l += len(line) + 2
+	}
 	res := make([]byte, 0, l)
 	res = append(res, header...)
-	res = append(res, bitmap...)
-	res = append(res, tail...)
-	return res, nil
+	res = append(res, body...)
+	for _, line := range opt.Extra {
+		res = append(res, line...)
+		res = append(res, "\r\n"...)
+	}
+	return append(res, tail...)
+}
+
+// printTail returns the PRINT commands for the sets of opt left after
+// opt.PrintOffset, one per set with DELAYs in between if opt.Delay is set.
+func (t *Driver) printTail(opt Options) string {
+	sets, copies := cmp.Or(opt.Sets, 1), cmp.Or(opt.Copies, 1)
+	if opt.Delay == 0 || sets-opt.PrintOffset == 1 {
+		return t.PrintOffset(copies, sets, opt.PrintOffset)
+	}
+	sets -= opt.PrintOffset
+	var b strings.Builder
+	for i := 0; i < sets; i++ {
+		if i > 0 {
+			b.WriteString(t.Delay(opt.Delay))
+		}
+		fmt.Fprintf(&b, "%s 1,%d\r\n", CmdPrint, copies)
+	}
+	return b.String()
+}
+
+// Threshold converts src to a Binary for this driver: pixels with a luma of
+// at least thresh are paper and the rest ink, stored as on or off bits
+// according to InkIsOn.
+func (t *Driver) Threshold(src image.Image, thresh uint8) (*bin_img.Binary, error) {
+	b, err := bin_img.FromGrayThreshold(src, thresh)
+	if err != nil {
+		return nil, err
+	}
+	if t.InkIsOn {
+		for i := range b.Pix {
+			b.Pix[i] = ^b.Pix[i]
+		}
+	}
+	return b, nil
+}
+
+// BitmapHeader returns the header of a BITMAP command at (x,y) with height
+// rows of rowBytes bytes, up to and including the comma the data follows, so
+// its size is known without an image at hand.
+func (t *Driver) BitmapHeader(rowBytes, height, x, y, mode int) string {
+	return fmt.Sprintf("%s %d,%d,%d,%d,%d,", CmdBitmap, x, y, rowBytes, height, mode)
 }
 
 func (t *Driver) Image2Bytes(img image.Image) (headerSize int, bitmap []byte, err error) {
-	var bwImg *bin_img.Binary
-	var gErr error
-	var isBwImage bool
-	if bwImg, isBwImage = img.(*bin_img.Binary); !isBwImage {
-		bwImg, gErr = bin_img.FromGrayThreshold(img, 151)
-	}
-	if gErr != nil {
-		err = gErr
+	return t.image2Bytes(context.Background(), img)
+}
+
+// ctxCheckRows is how many rows image2Bytes thresholds or packs between
+// context checks.
+const ctxCheckRows = 64
+
+func (t *Driver) image2Bytes(ctx context.Context, img image.Image) (headerSize int, bitmap []byte, err error) {
+	if err = ctx.Err(); err != nil {
 		return
 	}
+	bwImg, flip, err := t.toBinary(ctx, img)
+	if err != nil {
+		return 0, nil, err
+	}
 	bounds := bwImg.Bounds()
 	width, height := bounds.Dx(), bounds.Dy()
 	rowBytes := (width + 7) / 8
 
-	header := fmt.Sprintf("BITMAP 0,0,%d,%d,1,", rowBytes, height)
+	header := t.BitmapHeader(rowBytes, height, 0, 0, BitmapModeOR)
 	headerSize = len(header)
 
 	bitmap = make([]byte, headerSize+rowBytes*height)
@@ -73,8 +478,13 @@ func (t *Driver) Image2Bytes(img image.Image) (headerSize int, bitmap []byte, er
 	copy(bitmap[0:], header)
 
 	for y := 0; y < height; y++ {
+		if y%ctxCheckRows == 0 {
+			if err = ctx.Err(); err != nil {
+				return 0, nil, err
+			}
+		}
 		for x := 0; x < width; x++ {
-			if bwImg.IsWhite(x, y) {
+			if bwImg.IsWhite(bounds.Min.X+x, bounds.Min.Y+y) != flip {
 				byteIndex := headerSize + y*rowBytes + x/8
 				bitIndex := 7 - (x % 8)
 				bitmap[byteIndex] |= 1 << bitIndex
@@ -85,6 +495,80 @@ func (t *Driver) Image2Bytes(img image.Image) (headerSize int, bitmap []byte, er
 	return
 }
 
+// toBinary returns img as a Binary, thresholding other image types, and
+// whether its on pixels are ink. Only images the caller passed in as Binary
+// follow InkIsOn; thresholded ones are always on for white. Thresholding
+// goes ctxCheckRows rows at a time and stops with ctx.Err() once ctx is done.
+func (t *Driver) toBinary(ctx context.Context, img image.Image) (b *bin_img.Binary, flip bool, err error) {
+	if b, ok := img.(*bin_img.Binary); ok {
+		return b, t.InkIsOn, nil
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if b, err = bin_img.NewBinary(w, h); err != nil {
+		return nil, false, err
+	}
+	for y := 0; y < h; y += ctxCheckRows {
+		if err = ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		stripe := b.SubImage(image.Rect(0, y, w, y+ctxCheckRows)).(*bin_img.Binary)
+		stripe.FromGrayThreshold(img, 151)
+	}
+	return b, false, nil
+}
+
+// Image2BytesReader builds a BITMAP command from raw packed rows read from r,
+// without decoding them: rowBytes bytes per row, MSB first, 1 for white.
+// Exactly rowBytes*height bytes are read; a short read is an error.
+func (t *Driver) Image2BytesReader(r io.Reader, width, height, rowBytes int) ([]byte, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid image size got width: %d, height: %d", width, height)
+	}
+	if rowBytes < (width+7)/8 {
+		return nil, fmt.Errorf("rowBytes %d too small for width %d", rowBytes, width)
+	}
+	if rowBytes > math.MaxInt/height {
+		return nil, fmt.Errorf("invalid image size got width: %d, height: %d", width, height)
+	}
+	header := t.BitmapHeader(rowBytes, height, 0, 0, BitmapModeOR)
+	bitmap := make([]byte, len(header)+rowBytes*height)
+	copy(bitmap, header)
+	if _, err := io.ReadFull(r, bitmap[len(header):]); err != nil {
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return nil, fmt.Errorf("bitmap data too short: need %d bytes", rowBytes*height)
+		}
+		return nil, err
+	}
+	return bitmap, nil
+}
+
+// SplitBitmap splits a BITMAP command into consecutive BITMAP commands of at
+// most maxRows rows each, with their y positions adjusted so they print as
+// the original did.
+func (t *Driver) SplitBitmap(bitmapCmd []byte, maxRows int) ([]byte, error) {
+	if maxRows <= 0 {
+		return nil, errors.New("maxRows must be > 0")
+	}
+	h, err := t.ParseBitmapHeader(bitmapCmd)
+	if err != nil {
+		return nil, err
+	}
+	if h.Height <= maxRows {
+		return bitmapCmd, nil
+	}
+	data := bitmapCmd[h.HeaderEnd:]
+	res := make([]byte, 0, len(bitmapCmd)+(h.Height/maxRows+1)*h.HeaderEnd)
+	for y := 0; y < h.Height; y += maxRows {
+		rows := h.Height - y
+		if rows > maxRows {
+			rows = maxRows
+		}
+		res = append(res, t.BitmapHeader(h.RowBytes, rows, h.X, h.Y+y, h.Mode)...)
+		res = append(res, data[y*h.RowBytes:(y+rows)*h.RowBytes]...)
+	}
+	return append(res, data[h.RowBytes*h.Height:]...), nil
+}
+
 type Image struct {
 	Header []byte
 	Bitmap *bin_img.Binary
@@ -92,13 +576,69 @@ type Image struct {
 }
 
 type BitmapHeader struct {
+	X, Y                               int
 	RowBytes, Width, Height, HeaderEnd int
+	// Mode is the BITMAP drawing mode, one of the BitmapMode constants.
+	Mode int
 }
 
+// BITMAP modes. Modes 0 to 2 carry plain packed rows and only differ in how
+// they combine with the image buffer; mode 3 carries compressed rows, which
+// only some firmwares accept.
+const (
+	BitmapModeOverwrite  = 0
+	BitmapModeOR         = 1
+	BitmapModeXOR        = 2
+	BitmapModeCompressed = 3
+)
+
+// ParseBitmapHeader parses the header of the BITMAP command body and checks
+// with VerifyBitmapData that body holds all its data.
 func (t *Driver) ParseBitmapHeader(body []byte) (*BitmapHeader, error) {
-	var rowBytes, height, headerEnd int
+	h, err := parseBitmapHeader(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyBitmapData(h, body); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
 
-	if bytes.HasPrefix(body, []byte("BITMAP")) {
+// ParseBitmapHeaderStrict is like ParseBitmapHeader but also rejects a mode
+// that is not one of the BitmapMode constants, to catch authoring errors in
+// externally generated programs before they reach a printer.
+func (t *Driver) ParseBitmapHeaderStrict(body []byte) (*BitmapHeader, error) {
+	h, err := t.ParseBitmapHeader(body)
+	if err != nil {
+		return nil, err
+	}
+	if h.Mode < BitmapModeOverwrite || h.Mode > BitmapModeCompressed {
+		return nil, fmt.Errorf("invalid BITMAP mode %d", h.Mode)
+	}
+	return h, nil
+}
+
+// VerifyBitmapData checks that data, a BITMAP command starting with the
+// header described by header, is long enough for the rows the header
+// declares.
+func VerifyBitmapData(header *BitmapHeader, data []byte) error {
+	if header.RowBytes <= 0 || header.Height <= 0 {
+		return fmt.Errorf("invalid image size got width: %d, height: %d", header.Width, header.Height)
+	}
+	if header.RowBytes > (math.MaxInt-header.HeaderEnd)/header.Height {
+		return fmt.Errorf("invalid image size got width: %d, height: %d", header.Width, header.Height)
+	}
+	if need := header.HeaderEnd + header.RowBytes*header.Height; len(data) < need {
+		return fmt.Errorf("bitmap data too short: need %d bytes, got %d", need, len(data))
+	}
+	return nil
+}
+
+func parseBitmapHeader(body []byte) (*BitmapHeader, error) {
+	var x, y, rowBytes, height, headerEnd, mode int
+
+	if bytes.HasPrefix(body, []byte(CmdBitmap)) {
 		commaCount := 0
 		headerEnd = -1
 		for i, b := range body {
@@ -114,9 +654,8 @@ func (t *Driver) ParseBitmapHeader(body []byte) (*BitmapHeader, error) {
 			return nil, errors.New("invalid BITMAP format")
 		}
 		headerEnd = headerEnd + 1
-		var x, y, mode int
 		// Example: BITMAP 0,0,90,300,1,
-		if _, err := fmt.Sscanf(string(body), "BITMAP %d,%d,%d,%d,%d,",
+		if _, err := fmt.Sscanf(string(body[:headerEnd]), string(CmdBitmap)+" %d,%d,%d,%d,%d,",
 			&x, &y, &rowBytes, &height, &mode); err != nil {
 			return nil, err
 		}
@@ -124,10 +663,13 @@ func (t *Driver) ParseBitmapHeader(body []byte) (*BitmapHeader, error) {
 		return nil, errors.New("not a BITMAP line")
 	}
 	return &BitmapHeader{
+		X:         x,
+		Y:         y,
 		RowBytes:  rowBytes,
 		Width:     rowBytes * 8,
 		Height:    height,
 		HeaderEnd: headerEnd,
+		Mode:      mode,
 	}, nil
 }
 
@@ -136,7 +678,33 @@ func (t *Driver) ParseBitmapHeader(body []byte) (*BitmapHeader, error) {
 // `base` must start with a TSPL `BITMAP ...` header followed by bitmap bytes.
 // The overlay is written starting at (xOff,yOff) in pixels (bits). Overlay pixels overwrite base pixels
 // (both 0 and 1 are applied).
+//
+// When xOff and the overlay's left edge are multiples of 8, whole bytes are
+// copied per row, which is much faster than the bit-by-bit path other offsets
+// take.
 func (t *Driver) OverlayBinary(baseHeader *BitmapHeader, base []byte, overlay *bin_img.Binary, xOff, yOff int) ([]byte, error) {
+	return t.OverlayBinaryInvert(baseHeader, base, overlay, xOff, yOff, false)
+}
+
+// OverlayBinaryInvert is like OverlayBinary but writes the overlay inverted
+// if invert is set, for a base produced by a tool using the opposite bit
+// convention. It combines with InkIsOn: setting both cancels out.
+func (t *Driver) OverlayBinaryInvert(baseHeader *BitmapHeader, base []byte, overlay *bin_img.Binary, xOff, yOff int, invert bool) ([]byte, error) {
+	baseHeader, err := t.checkOverlay(baseHeader, base, overlay, xOff, yOff)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy base to result and patch only bitmap region.
+	res := make([]byte, len(base))
+	copy(res, base)
+	overlayInto(res, baseHeader, overlay, xOff, yOff, t.InkIsOn != invert)
+	return res, nil
+}
+
+// checkOverlay validates the arguments of OverlayBinary and returns the base
+// header, parsing it from base if baseHeader is nil.
+func (t *Driver) checkOverlay(baseHeader *BitmapHeader, base []byte, overlay *bin_img.Binary, xOff, yOff int) (*BitmapHeader, error) {
 	if overlay == nil {
 		return nil, errors.New("overlay is nil")
 	}
@@ -155,13 +723,10 @@ func (t *Driver) OverlayBinary(baseHeader *BitmapHeader, base []byte, overlay *b
 		}
 	}
 
-	baseRowBytes, baseW, baseH, baseHeaderEnd := baseHeader.RowBytes, baseHeader.Width, baseHeader.Height, baseHeader.HeaderEnd
-
-	// Validate base bitmap byte length.
-	baseBitmapLen := baseRowBytes * baseH
-	if baseHeaderEnd+baseBitmapLen > len(base) {
-		return nil, fmt.Errorf("base body too short: need %d bytes, got %d", baseHeaderEnd+baseBitmapLen, len(base))
+	if err := VerifyBitmapData(baseHeader, base); err != nil {
+		return nil, err
 	}
+	baseW, baseH := baseHeader.Width, baseHeader.Height
 
 	b := overlay.Bounds()
 	ovW, ovH := b.Dx(), b.Dy()
@@ -173,17 +738,39 @@ func (t *Driver) OverlayBinary(baseHeader *BitmapHeader, base []byte, overlay *b
 	if xOff+ovW > baseW || yOff+ovH > baseH {
 		return nil, fmt.Errorf("overlay out of bounds: base=%dx%d, overlay=%dx%d, off=(%d,%d)", baseW, baseH, ovW, ovH, xOff, yOff)
 	}
+	return baseHeader, nil
+}
 
-	// Copy base to result and patch only bitmap region.
-	res := make([]byte, len(base))
-	copy(res, base)
+// overlayInto writes overlay into the bitmap data of res at (xOff,yOff),
+// inverting it if ink is set. The arguments must have passed checkOverlay.
+func overlayInto(res []byte, baseHeader *BitmapHeader, overlay *bin_img.Binary, xOff, yOff int, ink bool) {
+	baseRowBytes, baseHeaderEnd := baseHeader.RowBytes, baseHeader.HeaderEnd
+	b := overlay.Bounds()
+	ovW, ovH := b.Dx(), b.Dy()
+
+	// Byte-aligned fast path: copy whole bytes and only merge the partial
+	// last byte of each row bit by bit.
+	x0 := 0
+	if xOff%8 == 0 && b.Min.X%8 == 0 {
+		full := ovW / 8
+		for y := 0; y < ovH; y++ {
+			dst := baseHeaderEnd + (y+yOff)*baseRowBytes + xOff/8
+			copy(res[dst:dst+full], overlay.Pix[y*overlay.Stride:])
+			if ink {
+				for i := dst; i < dst+full; i++ {
+					res[i] = ^res[i]
+				}
+			}
+		}
+		x0 = full * 8
+	}
 
 	// Bit-accurate overlay (works for any xOff alignment).
 	for y := 0; y < ovH; y++ {
-		for x := 0; x < ovW; x++ {
+		for x := x0; x < ovW; x++ {
 			// Keep consistent with Image2Bytes(): IsWhite -> bit 1
 			var bit uint8
-			if overlay.IsWhite(x+b.Min.X, y+b.Min.Y) {
+			if overlay.IsWhite(x+b.Min.X, y+b.Min.Y) != ink {
 				bit = 1
 			} else {
 				bit = 0
@@ -191,8 +778,6 @@ func (t *Driver) OverlayBinary(baseHeader *BitmapHeader, base []byte, overlay *b
 			setBitmapBit(res, baseHeaderEnd, baseRowBytes, x+xOff, y+yOff, bit)
 		}
 	}
-
-	return res, nil
 }
 
 // OverlayBinaryAtTopLeft overlays `overlay` onto `base` starting at (0,0).
@@ -211,7 +796,99 @@ func setBitmapBit(body []byte, headerEnd, rowBytes, x, y int, bit uint8) {
 	body[byteIndex] |= mask
 }
 
+// Bytes2Image decodes the BITMAP command at the start of body. The stacked
+// BITMAP commands that SplitBitmap and Options.MaxBitmapRows produce are
+// merged back into one image: each BITMAP that directly follows with the
+// same x, row width and mode and starts on the row below the previous one
+// adds its rows. Header is then the first command's header, and Tail starts
+// after the last one.
 func (t *Driver) Bytes2Image(body []byte) (*Image, error) {
+	return t.Bytes2ImageProgress(body, nil)
+}
+
+// Bytes2ImageStrict is like Bytes2Image but also checks that the bytes after
+// the bitmap data hold only whole commands, such as PRINT, DELAY and those
+// of Options.Extra: each must be a known TSPL command ended by a line feed,
+// with no binary data outside BITMAP and DOWNLOAD payloads. A label with
+// rows missing but an intact header borrows its trailing commands as pixel
+// data, so what is left is cut off mid-command and is reported here rather
+// than silently decoded.
+func (t *Driver) Bytes2ImageStrict(body []byte) (*Image, error) {
+	img, err := t.Bytes2Image(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyTail(img.Tail); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Bytes2ImageWidth is like Bytes2Image but for artwork logicalWidth dots
+// wide, which BITMAP cannot carry: its rows are whole bytes, so up to 7
+// padding columns on the right would otherwise show up as pixels. The
+// returned Bitmap shares the decoded rows but its bounds end at
+// logicalWidth, which must be within the last byte of a row.
+func (t *Driver) Bytes2ImageWidth(body []byte, logicalWidth int) (*Image, error) {
+	img, err := t.Bytes2Image(body)
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bitmap.Bounds()
+	if logicalWidth <= b.Dx()-8 || logicalWidth > b.Dx() {
+		return nil, fmt.Errorf("logical width %d does not fit BITMAP rows %d dots wide", logicalWidth, b.Dx())
+	}
+	b.Max.X = logicalWidth
+	img.Bitmap = img.Bitmap.SubImage(b).(*bin_img.Binary)
+	return img, nil
+}
+
+// verifyTail checks that tail, the bytes after a BITMAP's data, holds only
+// whole commands, as described at Bytes2ImageStrict.
+func verifyTail(tail []byte) error {
+	for len(tail) > 0 {
+		n, cmd, err := splitCommand(tail, true)
+		if err != nil {
+			return err
+		}
+		line := tail[:n]
+		tail = tail[n:]
+		if cmd == nil {
+			continue
+		}
+		name, _ := splitWord(cmd)
+		if !isCommand(name) {
+			return fmt.Errorf("unexpected command after bitmap data: %q", cmd)
+		}
+		// splitCommand has taken the payloads of well-formed BITMAP and
+		// DOWNLOAD commands by their declared size.
+		if CmdBitmap.is(name) {
+			if _, err := bitmapCommandLen(cmd); err == nil {
+				continue
+			}
+		}
+		if CmdDownload.is(name) {
+			if _, _, err := downloadHeader(cmd); err == nil {
+				continue
+			}
+		}
+		if bytes.IndexFunc(cmd, func(r rune) bool { return r < ' ' && r != '\t' }) >= 0 {
+			return fmt.Errorf("binary data after bitmap data: %q", cmd)
+		}
+		if !bytes.HasSuffix(line, []byte("\n")) {
+			return fmt.Errorf("truncated command after bitmap data: %q", cmd)
+		}
+	}
+	return nil
+}
+
+// Bytes2ImageProgress is like Bytes2Image but calls onRow after each bitmap
+// row is decoded, with y counting from 1 up to total. onRow may be nil.
+//
+// onRow runs synchronously on the calling goroutine and no locks are held
+// while it runs; it must not modify body. Decoding waits for onRow to return,
+// so slow callbacks slow the decode down.
+func (t *Driver) Bytes2ImageProgress(body []byte, onRow func(y, total int)) (*Image, error) {
 	h, err := t.ParseBitmapHeader(body)
 	if err != nil {
 		return nil, err
@@ -222,24 +899,48 @@ func (t *Driver) Bytes2Image(body []byte) (*Image, error) {
 	if width <= 0 || height <= 0 {
 		return nil, fmt.Errorf("invalid image size got width: %d, height: %d", width, height)
 	}
+	if rowBytes > math.MaxInt/8 || rowBytes > math.MaxInt/height {
+		return nil, fmt.Errorf("invalid image size got width: %d, height: %d", width, height)
+	}
+	if h.Mode < BitmapModeOverwrite || h.Mode > BitmapModeXOR {
+		return nil, fmt.Errorf("unsupported BITMAP compression mode %d", h.Mode)
+	}
+	// stripe is the rows of one of the merged BITMAP commands.
+	type stripe struct{ start, rows int }
+	stripes := []stripe{{headerEnd, height}}
+	bodyEnd := headerEnd + rowBytes*height
+	for last := h; ; {
+		next, err := parseBitmapHeader(body[bodyEnd:])
+		if err != nil || next.X != h.X || next.RowBytes != rowBytes || next.Mode != h.Mode ||
+			next.Y != last.Y+last.Height || VerifyBitmapData(next, body[bodyEnd:]) != nil ||
+			next.Height > math.MaxInt/rowBytes-height {
+			break
+		}
+		stripes = append(stripes, stripe{bodyEnd + next.HeaderEnd, next.Height})
+		height += next.Height
+		bodyEnd += next.HeaderEnd + rowBytes*next.Height
+		last = next
+	}
+
 	img, err := bin_img.NewBinary(width, height)
 	if err != nil {
 		return nil, err
 	}
 
-	bodyEnd := headerEnd
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			byteIndex := headerEnd + y*rowBytes + x/8
-			if byteIndex >= len(body) {
-				continue
+	y := 0
+	for _, s := range stripes {
+		for r := 0; r < s.rows; r, y = r+1, y+1 {
+			row := body[s.start+r*rowBytes:]
+			for x := 0; x < width; x++ {
+				bitIndex := 7 - (x % 8)
+				if ((row[x/8]>>bitIndex)&1 == 0) != t.InkIsOn {
+					img.SetOff(x, y)
+				} else {
+					img.SetOn(x, y)
+				}
 			}
-			bitIndex := 7 - (x % 8)
-			bodyEnd = byteIndex
-			if (body[byteIndex]>>bitIndex)&1 == 0 {
-				img.SetOff(x, y)
-			} else {
-				img.SetOn(x, y)
+			if onRow != nil {
+				onRow(y+1, height)
 			}
 		}
 	}
@@ -247,6 +948,6 @@ func (t *Driver) Bytes2Image(body []byte) (*Image, error) {
 	return &Image{
 		Header: body[:headerEnd],
 		Bitmap: img,
-		Tail:   body[bodyEnd+1:],
+		Tail:   body[bodyEnd:],
 	}, nil
 }
@@ -0,0 +1,469 @@
+package tspl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/haxii/tspl/bin-img"
+)
+
+// sameBinary reports whether a and b have the same bounds and pixels.
+func sameBinary(a, b *bin_img.Binary) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+	r := a.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if a.IsWhite(x, y) != b.IsWhite(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// samePixels reports whether view, which may have any origin, shows the
+// pixels of img from img's top-left corner.
+func samePixels(view, img *bin_img.Binary) bool {
+	r := view.Bounds()
+	o := img.Bounds().Min
+	if r.Dx() > img.Bounds().Dx() || r.Dy() > img.Bounds().Dy() {
+		return false
+	}
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			if view.IsWhite(r.Min.X+x, r.Min.Y+y) != img.IsWhite(o.X+x, o.Y+y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func FuzzBytes2Image(f *testing.F) {
+	for _, size := range []image.Point{{8, 1}, {16, 3}, {24, 5}} {
+		img := image.NewGray(image.Rect(0, 0, size.X, size.Y))
+		for i := range img.Pix {
+			img.Pix[i] = uint8(i * 53)
+		}
+		_, bitmap, err := DefaultDriver.Image2Bytes(img)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(bitmap, false)
+		f.Add(append(bitmap, "PRINT 1,1\r\n"...), true)
+	}
+	f.Add([]byte("BITMAP 0,0,1,1,0,\xff"), false)
+	f.Add([]byte("BITMAP 0,0,2,1,3,\x00"), false)
+	f.Add([]byte("BITMAP 0,0,-1,-1,0,"), false)
+	f.Add([]byte("BITMAP 0,0,9999999999,9999999999,0,"), false)
+
+	f.Fuzz(func(t *testing.T, body []byte, inkIsOn bool) {
+		d := &Driver{InkIsOn: inkIsOn}
+		first, err := d.Bytes2Image(body)
+		if err != nil {
+			return
+		}
+		_, again, err := d.Image2Bytes(first.Bitmap)
+		if err != nil {
+			t.Fatalf("re-encoding a decoded image: %v", err)
+		}
+		second, err := d.Bytes2Image(again)
+		if err != nil {
+			t.Fatalf("decoding a re-encoded image: %v", err)
+		}
+		if !sameBinary(first.Bitmap, second.Bitmap) {
+			t.Fatal("round trip changed the image")
+		}
+		if !bytes.HasPrefix(body, first.Header) {
+			t.Fatal("Header is not a prefix of body")
+		}
+	})
+}
+
+func TestHeaderOffsetShift(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	i := func(v int) *int { return &v }
+	for _, tt := range []struct {
+		opt  Options
+		want []string
+	}{
+		{Options{}, nil},
+		{Options{Offset: f(1.5)}, []string{"OFFSET 1.5 mm"}},
+		{Options{Offset: f(-0.25)}, []string{"OFFSET -0.2 mm"}},
+		{Options{Offset: f(0)}, []string{"OFFSET 0.0 mm"}},
+		{Options{Shift: i(-12)}, []string{"SHIFT -12"}},
+		{Options{Offset: f(2), Shift: i(8)}, []string{"OFFSET 2.0 mm", "SHIFT 8"}},
+	} {
+		var got []string
+		for _, line := range strings.Split(DefaultDriver.Header(80, 80, 8, tt.opt), "\r\n") {
+			if strings.HasPrefix(line, "OFFSET") || strings.HasPrefix(line, "SHIFT") {
+				got = append(got, line)
+			}
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestValidateOffsetShift(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	i := func(v int) *int { return &v }
+	for _, tt := range []struct {
+		opt Options
+		dpm int
+		ok  bool
+	}{
+		{Options{Offset: f(25.4)}, 8, true},
+		{Options{Offset: f(-25.4)}, 8, true},
+		{Options{Offset: f(25.5)}, 8, false},
+		{Options{Offset: f(math.NaN())}, 8, false},
+		{Options{Offset: f(math.Inf(1))}, 8, false},
+		{Options{Shift: i(203)}, 8, true},
+		{Options{Shift: i(204)}, 8, false},
+		{Options{Shift: i(-203)}, 0, true},
+		{Options{Shift: i(304)}, 12, true},
+		{Options{Shift: i(305)}, 12, false},
+	} {
+		if err := tt.opt.Validate(tt.dpm); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v, %d) = %v", tt.opt, tt.dpm, err)
+		}
+	}
+}
+
+func TestEncodeRejectsWideImages(t *testing.T) {
+	// A 100 dot label is not a whole number of bytes wide.
+	const w, h = 100, 40
+	for _, tt := range []struct {
+		width int
+		ok    bool
+	}{{96, true}, {100, true}, {101, false}, {104, false}} {
+		// Gray images must be whole bytes wide; others are cut from a Binary.
+		var img image.Image = image.NewGray(image.Rect(0, 0, tt.width, h))
+		if tt.width%8 != 0 {
+			b, err := bin_img.NewBinary((tt.width+7)&^7, h)
+			if err != nil {
+				t.Fatal(err)
+			}
+			img = b.SubImage(image.Rect(0, 0, tt.width, h))
+		}
+		_, err := DefaultDriver.Encode(w, h, 8, img, Options{})
+		if (err == nil) != tt.ok {
+			t.Errorf("Encode of a %d dot image: err = %v, want ok = %v", tt.width, err, tt.ok)
+		}
+		_, _, err = DefaultDriver.EncodeWithReceipt(w, h, 8, img, Options{})
+		if (err == nil) != tt.ok {
+			t.Errorf("EncodeWithReceipt of a %d dot image: err = %v, want ok = %v", tt.width, err, tt.ok)
+		}
+		if tt.width%8 == 0 {
+			_, err = DefaultDriver.EncodeGrayscale(w, h, 8, img, 4, Options{})
+			if (err == nil) != tt.ok {
+				t.Errorf("EncodeGrayscale of a %d dot image: err = %v, want ok = %v", tt.width, err, tt.ok)
+			}
+		}
+	}
+
+	// EncodeWithBitmap only knows the width in bytes, so it lets the last
+	// byte overhang.
+	for _, tt := range []struct {
+		width int
+		ok    bool
+	}{{104, true}, {112, false}} {
+		_, bitmap, err := DefaultDriver.Image2Bytes(image.NewGray(image.Rect(0, 0, tt.width, h)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = DefaultDriver.EncodeWithBitmap(w, h, 8, bitmap, Options{})
+		if (err == nil) != tt.ok {
+			t.Errorf("EncodeWithBitmap of a %d dot bitmap: err = %v, want ok = %v", tt.width, err, tt.ok)
+		}
+	}
+}
+
+// countdownCtx is a context that is done from its n-th Err call on.
+type countdownCtx struct {
+	context.Context
+	n int
+}
+
+func (c *countdownCtx) Err() error {
+	if c.n--; c.n <= 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+// countingImage counts the pixels read from it.
+type countingImage struct {
+	image.Image
+	reads int
+}
+
+func (c *countingImage) At(x, y int) color.Color {
+	c.reads++
+	return c.Image.At(x, y)
+}
+
+func TestEncodeContextStopsThresholding(t *testing.T) {
+	const w, h = 64, 64 * ctxCheckRows
+	img := &countingImage{Image: image.NewRGBA(image.Rect(0, 0, w, h))}
+	ctx := &countdownCtx{Context: context.Background(), n: 3}
+	_, err := DefaultDriver.EncodeContext(ctx, w, h, 8, img, Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("EncodeContext = %v, want context.Canceled", err)
+	}
+	if img.reads > 2*w*ctxCheckRows {
+		t.Errorf("read %d pixels after cancellation, want at most two stripes of %d", img.reads, w*ctxCheckRows)
+	}
+}
+
+func TestBytes2ImageMergesStripes(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 10))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 89)
+	}
+	_, whole, err := DefaultDriver.Image2Bytes(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := DefaultDriver.Bytes2Image(whole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := DefaultDriver.Encode(16, 10, 8, img, Options{MaxBitmapRows: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := doc[bytes.Index(doc, []byte(CmdBitmap)):]
+	if n := bytes.Count(body, []byte(CmdBitmap)); n != 4 {
+		t.Fatalf("%d BITMAP commands, want 4", n)
+	}
+	got, err := DefaultDriver.Bytes2ImageStrict(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameBinary(got.Bitmap, want.Bitmap) {
+		t.Error("merged stripes differ from the unsplit bitmap")
+	}
+	if string(got.Tail) != "PRINT 1,1\r\n" {
+		t.Errorf("Tail = %q, want the PRINT command", got.Tail)
+	}
+
+	// A BITMAP elsewhere on the label is not part of the image.
+	other := append(append([]byte(nil), whole...), "BITMAP 0,20,2,1,1,\xff\xff"...)
+	got, err = DefaultDriver.Bytes2Image(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Bitmap.Bounds().Dy() != 10 || !bytes.HasPrefix(got.Tail, []byte(CmdBitmap)) {
+		t.Errorf("decoded %d rows with tail %q, want 10 rows and the second BITMAP left over", got.Bitmap.Bounds().Dy(), got.Tail)
+	}
+}
+
+func TestBytes2ImageStrictTail(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 4))
+	doc, err := DefaultDriver.Encode(16, 4, 8, img, Options{
+		Extra: []string{`TEXT 10,10,"3",0,1,1,"Lot 42"`, "BOX 0,0,15,3,1", "SOUND 2,100"},
+		Sets:  2, Delay: 500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := doc[bytes.Index(doc, []byte(CmdBitmap)):]
+	if _, err := DefaultDriver.Bytes2ImageStrict(body); err != nil {
+		t.Fatalf("Bytes2ImageStrict of an encoded label: %v", err)
+	}
+	h, err := DefaultDriver.ParseBitmapHeader(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bitmapEnd := h.HeaderEnd + h.RowBytes*h.Height
+
+	for _, tail := range []string{
+		"",
+		"PRINT 1,1\r\n",
+		"\r\nQRCODE 1,1,L,4,A,0,\"a,b\"\r\nPRINT 1\r\n",
+		"BITMAP 0,8,2,1,1,\r\nPRINT 1,1\r\n",
+		"DOWNLOAD \"A.BIN\",2,\x00\x01PRINT 1,1\r\n",
+	} {
+		if _, err := DefaultDriver.Bytes2ImageStrict(append(body[:bitmapEnd:bitmapEnd], tail...)); err != nil {
+			t.Errorf("tail %q: %v", tail, err)
+		}
+	}
+	for _, tail := range []string{
+		"NT 1,1\r\n",
+		"1,1\r\n",
+		"PRINT 1,1",
+		"PRINT 1\x00\xff\r\n",
+		"\xff\xfe\x00PRINT 1,1\r\n",
+	} {
+		if _, err := DefaultDriver.Bytes2ImageStrict(append(body[:bitmapEnd:bitmapEnd], tail...)); err == nil {
+			t.Errorf("tail %q accepted", tail)
+		}
+	}
+}
+
+func TestPrintOffset(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for _, tt := range []struct {
+		opt  Options
+		tail string
+	}{
+		{Options{Sets: 5, Copies: 2}, "PRINT 5,2\r\n"},
+		{Options{Sets: 5, Copies: 2, PrintOffset: 3}, "PRINT 2,2\r\n"},
+		{Options{Sets: 5, PrintOffset: 4}, "PRINT 1,1\r\n"},
+		{Options{Sets: 4, PrintOffset: 1, Delay: 100},
+			"PRINT 1,1\r\nDELAY 100\r\nPRINT 1,1\r\nDELAY 100\r\nPRINT 1,1\r\n"},
+		{Options{Sets: 4, PrintOffset: 3, Delay: 100}, "PRINT 1,1\r\n"},
+	} {
+		body, err := DefaultDriver.Encode(16, 16, 8, img, tt.opt)
+		if err != nil {
+			t.Fatalf("%+v: %v", tt.opt, err)
+		}
+		if !bytes.HasSuffix(body, []byte(tt.tail)) ||
+			bytes.Count(body, []byte(CmdPrint)) != strings.Count(tt.tail, string(CmdPrint)) {
+			t.Errorf("%+v: got %q, want it to end in %q", tt.opt, body, tt.tail)
+		}
+	}
+	for _, opt := range []Options{{PrintOffset: 1}, {Sets: 3, PrintOffset: 3}, {Sets: 3, PrintOffset: -1}} {
+		if err := opt.Validate(8); err == nil {
+			t.Errorf("Validate(%+v) succeeded", opt)
+		}
+	}
+}
+
+func TestColumnMajorRoundTrip(t *testing.T) {
+	full, err := bin_img.NewBinary(16, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 11; y++ {
+		for x := 0; x < 16; x++ {
+			if (x*7+y*3)%5 != 0 {
+				full.SetOn(x, y)
+			}
+		}
+	}
+	img := full.SubImage(image.Rect(0, 0, 13, 11)).(*bin_img.Binary)
+	for _, d := range []*Driver{{}, {InkIsOn: true}} {
+		data, err := d.Image2BytesColumnMajor(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 13 columns of 2 bytes each, without a BITMAP header.
+		if len(data) != 13*2 {
+			t.Fatalf("got %d bytes, want 26", len(data))
+		}
+		// Column 5 holds (5,y) for y = 0..10. (5,0) is off and (5,1) on, so
+		// ink and paper, or the other way round with InkIsOn.
+		ink, paper := byte(0), byte(1)
+		if d.InkIsOn {
+			ink, paper = paper, ink
+		}
+		if got := data[5*2] >> 7; got != ink {
+			t.Errorf("InkIsOn=%v: pixel (5,0) encoded as %d, want %d", d.InkIsOn, got, ink)
+		}
+		if got := data[5*2] >> 6 & 1; got != paper {
+			t.Errorf("InkIsOn=%v: pixel (5,1) encoded as %d, want %d", d.InkIsOn, got, paper)
+		}
+		back, err := d.Bytes2ImageColumnMajor(data, 13, 11)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameBinary(back, img) {
+			t.Errorf("InkIsOn=%v: round trip changed the image", d.InkIsOn)
+		}
+		if _, err := d.Bytes2ImageColumnMajor(data[:25], 13, 11); err == nil {
+			t.Error("decoding short data succeeded")
+		}
+	}
+}
+
+func TestEncodeSprite(t *testing.T) {
+	sheet, err := bin_img.NewSpriteSheet(64, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			if (x^y)&4 == 0 {
+				sheet.Sheet().SetOn(x, y)
+			}
+		}
+	}
+	if err := sheet.AddSprite("check", image.Rect(37, 9, 58, 30)); err != nil {
+		t.Fatal(err)
+	}
+	sprite, err := sheet.Sprite("check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := DefaultDriver.Encode(24, 24, 8, sprite, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := DefaultDriver.Bytes2Image(body[bytes.Index(body, []byte(CmdBitmap)):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !samePixels(sprite, img.Bitmap) {
+		t.Error("the encoded sprite differs from the sprite")
+	}
+}
+
+func TestEncodeWithReceiptView(t *testing.T) {
+	b, err := bin_img.NewBinary(64, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range b.Pix {
+		b.Pix[i] = byte(i * 37)
+	}
+	view := b.SubImage(image.Rect(11, 5, 51, 21)).(*bin_img.Binary)
+	doc, sum, err := DefaultDriver.EncodeWithReceipt(40, 16, 8, view, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err := bin_img.NewBinary(40, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved.CopyFrom(view, 0, 0, view.Rect)
+	want, wantSum, err := DefaultDriver.EncodeWithReceipt(40, 16, 8, moved, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(doc, want) || sum != wantSum {
+		t.Error("a view encodes differently from its pixels moved to the origin")
+	}
+}
+
+// BenchmarkBytes2ImageOneRowStripes decodes a program split into one BITMAP
+// per row, which parses a header per row.
+func BenchmarkBytes2ImageOneRowStripes(b *testing.B) {
+	for _, rows := range []int{1000, 8000} {
+		img := image.NewGray(image.Rect(0, 0, 800, rows))
+		doc, err := DefaultDriver.Encode(800, rows, 8, img, Options{MaxBitmapRows: 1})
+		if err != nil {
+			b.Fatal(err)
+		}
+		body := doc[bytes.Index(doc, []byte(CmdBitmap)):]
+		b.Run(fmt.Sprint(rows), func(b *testing.B) {
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				if _, err := DefaultDriver.Bytes2Image(body); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package tspl
+
+import (
+	"fmt"
+	"math"
+)
+
+// SizeUnit selects the unit Header gives the label size in.
+type SizeUnit int
+
+const (
+	// SizeUnitMM emits SIZE in millimetres, to 0.1 mm.
+	SizeUnitMM SizeUnit = iota
+	// SizeUnitInch emits SIZE in inches, to 0.01 inch. Not every model
+	// accepts it.
+	SizeUnitInch
+	// SizeUnitDots emits SIZE in whole dots, e.g. SIZE 832 dot, 1200 dot,
+	// so no millimetre rounding is involved. Not every model accepts it.
+	SizeUnitDots
+)
+
+func (u SizeUnit) String() string {
+	switch u {
+	case SizeUnitMM:
+		return "mm"
+	case SizeUnitInch:
+		return "inch"
+	case SizeUnitDots:
+		return "dot"
+	}
+	return fmt.Sprintf("SizeUnit(%d)", int(u))
+}
+
+func (u SizeUnit) valid() bool { return u >= SizeUnitMM && u <= SizeUnitDots }
+
+// fromDots converts dots at dpm dots per millimetre to u.
+func (u SizeUnit) fromDots(dots, dpm int) float64 {
+	switch u {
+	case SizeUnitInch:
+		return float64(dots) / float64(dpm) / 25.4
+	case SizeUnitDots:
+		return float64(dots)
+	}
+	return float64(dots) / float64(dpm)
+}
+
+// roundUp rounds v, in u, up to the precision SIZE is emitted with.
+func (u SizeUnit) roundUp(v float64) float64 {
+	steps := 10.0
+	switch u {
+	case SizeUnitInch:
+		steps = 100
+	case SizeUnitDots:
+		steps = 1
+	}
+	// The epsilon keeps exact steps from rounding up a further step.
+	return math.Ceil(v*steps-1e-9) / steps
+}
+
+// size formats the arguments of SIZE for a w x h label measured in u.
+func (u SizeUnit) size(w, h float64) string {
+	switch u {
+	case SizeUnitInch:
+		return fmt.Sprintf("%.2f, %.2f", w, h)
+	case SizeUnitDots:
+		return fmt.Sprintf("%d dot, %d dot", int(math.Round(w)), int(math.Round(h)))
+	}
+	return fmt.Sprintf("%.1f mm, %.1f mm", w, h)
+}
@@ -0,0 +1,62 @@
+package tspl
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+// sizeLine returns the SIZE line of header, without its line ending.
+func sizeLine(t *testing.T, header string) string {
+	t.Helper()
+	for _, line := range strings.Split(header, "\r\n") {
+		if strings.HasPrefix(line, string(CmdSize)+" ") {
+			return line
+		}
+	}
+	t.Fatalf("no SIZE line in %q", header)
+	return ""
+}
+
+func TestHeaderSizeUnits(t *testing.T) {
+	for _, tt := range []struct {
+		unit SizeUnit
+		w, h int
+		dpm  int
+		want string
+	}{
+		{SizeUnitMM, 320, 240, 8, "SIZE 40.0 mm, 30.0 mm"},
+		{SizeUnitMM, 812, 1218, 8, "SIZE 101.5 mm, 152.2 mm"},
+		{SizeUnitMM, 1200, 600, 12, "SIZE 100.0 mm, 50.0 mm"},
+		{SizeUnitInch, 812, 1218, 8, "SIZE 4.00, 5.99"},
+		{SizeUnitInch, 406, 203, 8, "SIZE 2.00, 1.00"},
+		{SizeUnitInch, 1200, 600, 12, "SIZE 3.94, 1.97"},
+		{SizeUnitDots, 832, 1200, 8, "SIZE 832 dot, 1200 dot"},
+		{SizeUnitDots, 5, 7, 12, "SIZE 5 dot, 7 dot"},
+	} {
+		got := sizeLine(t, DefaultDriver.Header(tt.w, tt.h, tt.dpm, Options{Unit: tt.unit}))
+		if got != tt.want {
+			t.Errorf("%v %dx%d at %d dpm: got %q, want %q", tt.unit, tt.w, tt.h, tt.dpm, got, tt.want)
+		}
+	}
+}
+
+func TestAutoHeaderRoundsUp(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 813, 1219))
+	for unit, want := range map[SizeUnit]string{
+		SizeUnitMM:   "SIZE 101.7 mm, 152.4 mm",
+		SizeUnitInch: "SIZE 4.01, 6.00",
+		SizeUnitDots: "SIZE 813 dot, 1219 dot",
+	} {
+		if got := sizeLine(t, DefaultDriver.AutoHeader(img, 8, Options{Unit: unit})); got != want {
+			t.Errorf("%v: got %q, want %q", unit, got, want)
+		}
+	}
+}
+
+func TestInvalidSizeUnit(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 8, 8))
+	if _, err := DefaultDriver.Encode(8, 8, 8, img, Options{Unit: SizeUnit(7)}); err == nil {
+		t.Fatal("Encode accepted an invalid unit")
+	}
+}
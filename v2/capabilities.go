@@ -0,0 +1,25 @@
+package tspl
+
+import "errors"
+
+// ErrUnsupportedCommand is returned when an option is requested for a
+// printer model that lacks the feature.
+var ErrUnsupportedCommand = errors.New("command not supported by printer model")
+
+// PrinterCapabilities describes what a printer model can do. It has the
+// fields of v1's PrinterCapabilities, so an entry of v1's model table
+// converts to it directly:
+//
+//	c, _ := v1.ModelCapabilities("TTP-247")
+//	caps := tspl.PrinterCapabilities(*c)
+type PrinterCapabilities struct {
+	Model              string
+	DPI                int
+	MaxPrintWidthMM    float64
+	MaxSpeedInchPerSec float64
+	MaxDensity         int
+	SupportsCutter     bool
+	SupportsPeel       bool
+	SupportsQRCode     bool
+	SupportsPDF417     bool
+}
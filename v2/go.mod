@@ -0,0 +1,3 @@
+module github.com/haxii/tspl/v2
+
+go 1.20
@@ -0,0 +1,191 @@
+// Package tspl is version 2 of github.com/haxii/tspl. It encodes a label to
+// the same bytes as the v1 encoder but moves the label geometry and print
+// settings that v1 spread over Encode's arguments and Options into a single
+// EncodeOptions. The module does not depend on v1. See MIGRATION.md for
+// moving from v1.
+package tspl
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+var DefaultDriver = &Driver{}
+
+type Driver struct {
+	// Capabilities, when set, makes Encode fail with ErrUnsupportedCommand
+	// for options the model cannot honour.
+	Capabilities *PrinterCapabilities
+	// InkIsOn flips the meaning of the bits of bit images, such as
+	// *bin_img.Binary, as in v1: on is ink and off is paper. Other image
+	// types are unaffected; their dark pixels always print.
+	InkIsOn bool
+	// MaxWidthMM and MaxHeightMM, when not 0, make Encode fail for labels
+	// larger than this many millimetres, as in v1.
+	MaxWidthMM, MaxHeightMM float64
+}
+
+// EncodeOptions describes a label and how to print it.
+type EncodeOptions struct {
+	// LabelW and LabelH are the label size in dots at DPM dots per
+	// millimetre.
+	LabelW, LabelH, DPM int
+	// Copies and Sets are the arguments of PRINT: Sets label sets of Copies
+	// copies each. Zero means 1.
+	Copies, Sets int
+	// Speed is the print speed in inches per second. Zero keeps the
+	// printer's setting.
+	Speed float64
+	// Density is the print darkness from 1 to 15. Zero keeps the printer's
+	// setting.
+	Density int
+	// Peel turns on peel-off mode and Cutter cuts after every label.
+	Peel, Cutter bool
+}
+
+// DefaultEncodeOptions returns options for one copy of an empty-sized label
+// at 8 dots per millimetre (203 dpi), leaving speed and density to the
+// printer. Set LabelW and LabelH before encoding.
+func DefaultEncodeOptions() EncodeOptions {
+	return EncodeOptions{DPM: 8, Copies: 1, Sets: 1}
+}
+
+// maxDPM is the largest dots-per-mm value accepted, as in v1.
+const maxDPM = 50
+
+// threshold is the luma from which a pixel is paper, as in v1.
+const threshold = 151
+
+// Encode renders img as a complete TSPL program for the label opts
+// describes.
+func (t *Driver) Encode(img image.Image, opts EncodeOptions) ([]byte, error) {
+	if err := t.check(img, opts); err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "SET CUTTER %s\r\n", onOff(opts.Cutter))
+	b.WriteString("SET PARTICAL_CUTTER OFF\r\n")
+	fmt.Fprintf(&b, "SET PEEL %s\r\n", onOff(opts.Peel))
+	dpm := float64(cmp.Or(opts.DPM, 8))
+	fmt.Fprintf(&b, "SIZE %.1f mm, %.1f mm\r\n", float64(opts.LabelW)/dpm, float64(opts.LabelH)/dpm)
+	if opts.Speed != 0 {
+		fmt.Fprintf(&b, "SPEED %g\r\n", opts.Speed)
+	}
+	if opts.Density != 0 {
+		fmt.Fprintf(&b, "DENSITY %d\r\n", opts.Density)
+	}
+	b.WriteString("CLS\r\n")
+	t.writeBitmap(&b, img)
+	fmt.Fprintf(&b, "PRINT %d,%d\r\n", cmp.Or(opts.Sets, 1), cmp.Or(opts.Copies, 1))
+	return []byte(b.String()), nil
+}
+
+// check validates opts and checks that img fits the label and that the
+// label and the model allow them.
+func (t *Driver) check(img image.Image, opts EncodeOptions) error {
+	if opts.DPM < 0 {
+		return fmt.Errorf("invalid dpm %d", opts.DPM)
+	}
+	if opts.DPM > maxDPM {
+		return fmt.Errorf("dpm %d is dots per millimetre, not dots per inch: "+
+			"use %d for a %d dpi printer", opts.DPM, int(math.Round(float64(opts.DPM)/25.4)), opts.DPM)
+	}
+	if opts.Speed < 0 || math.IsNaN(opts.Speed) || math.IsInf(opts.Speed, 0) {
+		return fmt.Errorf("invalid speed %v", opts.Speed)
+	}
+	if opts.Density < 0 || opts.Density > 15 {
+		return fmt.Errorf("density %d out of range 1 to 15", opts.Density)
+	}
+	if opts.Sets < 0 || opts.Copies < 0 {
+		return fmt.Errorf("invalid PRINT count %d,%d", opts.Sets, opts.Copies)
+	}
+	dpm := float64(cmp.Or(opts.DPM, 8))
+	if mm := float64(opts.LabelW) / dpm; t.MaxWidthMM > 0 && mm > t.MaxWidthMM {
+		return fmt.Errorf("label width %g mm exceeds the maximum of %g mm", mm, t.MaxWidthMM)
+	}
+	if mm := float64(opts.LabelH) / dpm; t.MaxHeightMM > 0 && mm > t.MaxHeightMM {
+		return fmt.Errorf("label height %g mm exceeds the maximum of %g mm", mm, t.MaxHeightMM)
+	}
+	if dx := img.Bounds().Dx(); dx > opts.LabelW {
+		return fmt.Errorf("image %d dots wide does not fit on a label %d dots wide", dx, opts.LabelW)
+	}
+	if img.Bounds().Empty() {
+		return fmt.Errorf("invalid image size %v", img.Bounds().Size())
+	}
+	c := t.Capabilities
+	if c == nil {
+		return nil
+	}
+	if opts.Peel && !c.SupportsPeel {
+		return fmt.Errorf("%w: PEEL on %s", ErrUnsupportedCommand, c.Model)
+	}
+	if opts.Cutter && !c.SupportsCutter {
+		return fmt.Errorf("%w: CUTTER on %s", ErrUnsupportedCommand, c.Model)
+	}
+	if opts.Speed != 0 && c.MaxSpeedInchPerSec > 0 && opts.Speed > c.MaxSpeedInchPerSec {
+		return fmt.Errorf("%w: SPEED %g on %s", ErrUnsupportedCommand, opts.Speed, c.Model)
+	}
+	if opts.Density != 0 && c.MaxDensity > 0 && opts.Density > c.MaxDensity {
+		return fmt.Errorf("%w: DENSITY %d on %s", ErrUnsupportedCommand, opts.Density, c.Model)
+	}
+	return nil
+}
+
+// bitImage is an image that exposes its bits, such as *bin_img.Binary.
+type bitImage interface {
+	image.Image
+	IsWhite(x, y int) bool
+}
+
+// writeBitmap writes img as a BITMAP command in OR mode, 8 pixels per byte,
+// MSB first, 1 for paper. The bits of a bitImage are taken as they are,
+// flipped for InkIsOn; other images are thresholded by luma.
+func (t *Driver) writeBitmap(b *strings.Builder, img image.Image) {
+	r := img.Bounds()
+	rowBytes := (r.Dx() + 7) / 8
+	fmt.Fprintf(b, "BITMAP 0,0,%d,%d,1,", rowBytes, r.Dy())
+	bits, isBits := img.(bitImage)
+	row := make([]byte, rowBytes)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var paper bool
+			if isBits {
+				paper = bits.IsWhite(x, y) != t.InkIsOn
+			} else {
+				paper = luma8(img.At(x, y)) >= threshold
+			}
+			if paper {
+				row[(x-r.Min.X)/8] |= 0x80 >> uint((x-r.Min.X)%8)
+			}
+		}
+		b.Write(row)
+	}
+}
+
+// luma8 returns the 8-bit luma of c's un-premultiplied color, or 255 for
+// paper if c is fully transparent, as bin_img does.
+func luma8(c color.Color) uint8 {
+	r, g, bl, a := c.RGBA()
+	if a == 0 {
+		return 0xff
+	}
+	if a != 0xffff {
+		r, g, bl = r*0xffff/a, g*0xffff/a, bl*0xffff/a
+	}
+	// (299, 587, 114) are standard coefficients scaled by 1000.
+	return uint8((299*r + 587*g + 114*bl) / 1000 >> 8)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
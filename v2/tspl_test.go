@@ -0,0 +1,104 @@
+package tspl
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// bits is a minimal bit image, standing in for *bin_img.Binary.
+type bits struct {
+	*image.Gray
+}
+
+func (b bits) IsWhite(x, y int) bool { return b.GrayAt(x, y).Y != 0 }
+
+func TestEncode(t *testing.T) {
+	// A white row over a row that is black on the left and transparent on
+	// the right.
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 2))
+	for x := 0; x < 8; x++ {
+		img.Set(x, 0, color.White)
+		if x < 4 {
+			img.Set(x, 1, color.NRGBA{A: 128})
+		}
+	}
+	opts := EncodeOptions{LabelW: 100, LabelH: 83, DPM: 8, Copies: 3, Sets: 2,
+		Speed: 4.5, Density: 9, Peel: true, Cutter: true}
+	want := "SET CUTTER ON\r\nSET PARTICAL_CUTTER OFF\r\nSET PEEL ON\r\n" +
+		"SIZE 12.5 mm, 10.4 mm\r\nSPEED 4.5\r\nDENSITY 9\r\nCLS\r\n" +
+		"BITMAP 0,0,1,2,1,\xff\x0fPRINT 2,3\r\n"
+	doc, err := DefaultDriver.Encode(img, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc) != want {
+		t.Errorf("Encode =\n%q\nwant\n%q", doc, want)
+	}
+
+	opts = DefaultEncodeOptions()
+	opts.LabelW, opts.LabelH = 16, 16
+	want = "SET CUTTER OFF\r\nSET PARTICAL_CUTTER OFF\r\nSET PEEL OFF\r\n" +
+		"SIZE 2.0 mm, 2.0 mm\r\nCLS\r\nBITMAP 0,0,1,2,1,\xff\x0fPRINT 1,1\r\n"
+	if doc, err = DefaultDriver.Encode(img, opts); err != nil || string(doc) != want {
+		t.Errorf("Encode with defaults = %q, %v, want %q", doc, err, want)
+	}
+}
+
+func TestEncodeBitImage(t *testing.T) {
+	g := image.NewGray(image.Rect(0, 0, 8, 1))
+	copy(g.Pix, []byte{255, 0, 255, 0, 255, 255, 0, 0})
+	opts := DefaultEncodeOptions()
+	opts.LabelW, opts.LabelH = 8, 8
+	for _, tt := range []struct {
+		inkIsOn bool
+		data    byte
+	}{{false, 0xac}, {true, 0x53}} {
+		d := &Driver{InkIsOn: tt.inkIsOn}
+		doc, err := d.Encode(bits{g}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "BITMAP 0,0,1,1,1," + string([]byte{tt.data}) + "PRINT"
+		if !strings.Contains(string(doc), want) {
+			t.Errorf("InkIsOn %v: %q does not contain %q", tt.inkIsOn, doc, want)
+		}
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 8))
+	base := DefaultEncodeOptions()
+	base.LabelW, base.LabelH = 16, 8
+	noCutter := &PrinterCapabilities{Model: "TDP-225", MaxSpeedInchPerSec: 5, MaxDensity: 15, SupportsPeel: true}
+	for _, tt := range []struct {
+		name        string
+		d           *Driver
+		edit        func(*EncodeOptions)
+		unsupported bool
+	}{
+		{"negative dpm", DefaultDriver, func(o *EncodeOptions) { o.DPM = -1 }, false},
+		{"dpi as dpm", DefaultDriver, func(o *EncodeOptions) { o.DPM = 203 }, false},
+		{"negative speed", DefaultDriver, func(o *EncodeOptions) { o.Speed = -1 }, false},
+		{"density", DefaultDriver, func(o *EncodeOptions) { o.Density = 16 }, false},
+		{"copies", DefaultDriver, func(o *EncodeOptions) { o.Copies = -1 }, false},
+		{"image wider than label", DefaultDriver, func(o *EncodeOptions) { o.LabelW = 15 }, false},
+		{"label too long", &Driver{MaxHeightMM: 0.5}, func(o *EncodeOptions) {}, false},
+		{"cutter", &Driver{Capabilities: noCutter}, func(o *EncodeOptions) { o.Cutter = true }, true},
+		{"speed", &Driver{Capabilities: noCutter}, func(o *EncodeOptions) { o.Speed = 6 }, true},
+	} {
+		opts := base
+		tt.edit(&opts)
+		_, err := tt.d.Encode(img, opts)
+		if err == nil {
+			t.Errorf("%s: Encode succeeded", tt.name)
+		} else if errors.Is(err, ErrUnsupportedCommand) != tt.unsupported {
+			t.Errorf("%s: Encode = %v, want ErrUnsupportedCommand %v", tt.name, err, tt.unsupported)
+		}
+	}
+	if _, err := (&Driver{Capabilities: noCutter}).Encode(img, base); err != nil {
+		t.Errorf("Encode with supported options: %v", err)
+	}
+}
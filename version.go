@@ -0,0 +1,42 @@
+package tspl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// versionPrefix starts the comment line EncodeWithVersion adds. Printers
+// skip lines starting with a semicolon.
+const versionPrefix = "; tspl-go version="
+
+// EncodeWithVersion is like Encode but starts the document with a comment
+// line recording version, e.g. the generator's release, so a document found
+// in production can be traced back to what made it. version must not contain
+// line breaks.
+func (t *Driver) EncodeWithVersion(w, h, dpm int, img image.Image, opt Options, version string) ([]byte, error) {
+	if strings.ContainsAny(version, "\r\n") {
+		return nil, errors.New("version must not contain line breaks")
+	}
+	doc, err := t.Encode(w, h, dpm, img, opt)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(fmt.Sprintf("%s%s\r\n", versionPrefix, version)), doc...), nil
+}
+
+// ExtractVersion returns the version recorded by EncodeWithVersion, or "" if
+// doc has none.
+func ExtractVersion(doc []byte) string {
+	var version string
+	scanCommands(doc, func(cmd []byte) bool {
+		if bytes.HasPrefix(cmd, []byte(versionPrefix)) {
+			version = string(cmd[len(versionPrefix):])
+			return false
+		}
+		return true
+	})
+	return version
+}